@@ -15,8 +15,11 @@ require (
 require (
 	github.com/araddon/dateparse v0.0.0-20210429162001-6b43995a97de
 	github.com/sirupsen/logrus v1.9.3
+	golang.org/x/net v0.28.0
 )
 
+require golang.org/x/text v0.17.0 // indirect
+
 require (
 	github.com/stripe/stripe-go/v72 v72.122.0
 	github.com/stripe/stripe-go/v74 v74.30.0
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"github.com/qmranik/rss-aggregator-backend/helper"
 	"github.com/qmranik/rss-aggregator-backend/internal/auth"
 	"github.com/qmranik/rss-aggregator-backend/internal/database"
 )
 
 // ApiConfig contains the database and authentication configurations for the API.
 type ApiConfig struct {
-	DB   *database.Queries   // DB is a pointer to the database queries interface.
-	Auth *auth.Authenticator // Auth is a pointer to the authentication manager.
+	DB          *database.Queries        // DB is a pointer to the database queries interface.
+	Auth        *auth.Authenticator      // Auth is a pointer to the authentication manager.
+	Fetcher     *helper.HTTPFetcher      // Fetcher is used to discover feeds from user-supplied URLs.
+	Scraper     *helper.Scraper          // Scraper stores the posts of content pushed by WebSub hubs.
+	Credentials *helper.CredentialCipher // Credentials, if set, encrypts the credentials of private feeds.
 }
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/go-chi/chi"
 	"github.com/google/uuid"
 	"github.com/qmranik/rss-aggregator-backend/helper"
 	"github.com/qmranik/rss-aggregator-backend/internal/database"
@@ -13,6 +17,9 @@ import (
 )
 
 // HandlerFeedCreate creates a new feed and automatically follows it for the user.
+// When the submitted URL is a web page, the feed it advertises is discovered and stored instead.
+// A feed submitted with credentials must be the feed URL itself; it is stored as a private
+// feed of the user with its credentials encrypted.
 func (cfg *ApiConfig) HandlerFeedCreate(w http.ResponseWriter, r *http.Request, user database.User) {
 	// Decode the incoming request body into the parameters struct
 	var params models.Parameters
@@ -26,14 +33,54 @@ func (cfg *ApiConfig) HandlerFeedCreate(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	// Only fetch absolute http(s) URLs that do not point at internal hosts
+	pageURL, err := helper.ValidateFeedURL(params.URL)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"func":    "HandlerFeedCreate",
+			"userID":  user.ID,
+			"feedURL": params.URL,
+		}).Warn("Invalid feed URL")
+		helper.RespondWithError(w, http.StatusBadRequest, "Invalid feed URL")
+		return
+	}
+
+	if params.Credentials != nil {
+		cfg.createPrivateFeed(w, r, user, params, pageURL)
+		return
+	}
+
+	// Resolve the submitted URL to a feed, discovering it when a web page was given
+	feedURL, candidates, err := cfg.Fetcher.DiscoverFeed(r.Context(), pageURL)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"func":    "HandlerFeedCreate",
+			"userID":  user.ID,
+			"feedURL": params.URL,
+		}).Warn("Couldn't discover feed")
+		if errors.Is(err, helper.ErrNoFeedFound) {
+			helper.RespondWithError(w, http.StatusBadRequest, "No feed found at URL")
+			return
+		}
+		if errors.Is(err, helper.ErrForbiddenAddress) {
+			helper.RespondWithError(w, http.StatusBadRequest, "Invalid feed URL")
+			return
+		}
+		helper.RespondWithError(w, http.StatusBadRequest, "Couldn't fetch URL")
+		return
+	}
+
 	// Create a new feed record in the database
 	feed, err := cfg.DB.CreateFeed(r.Context(), database.CreateFeedParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		UserID:    user.ID,
-		Name:      params.Name,
-		Url:       params.URL,
+		ID:               uuid.New(),
+		CreatedAt:        time.Now().UTC(),
+		UpdatedAt:        time.Now().UTC(),
+		UserID:           user.ID,
+		Name:             params.Name,
+		Url:              feedURL,
+		FetchFullContent: params.FetchFullContent,
 	})
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -41,13 +88,92 @@ func (cfg *ApiConfig) HandlerFeedCreate(w http.ResponseWriter, r *http.Request,
 			"func":     "HandlerFeedCreate",
 			"userID":   user.ID,
 			"feedName": params.Name,
-			"feedURL":  params.URL,
+			"feedURL":  feedURL,
 		}).Error("Couldn't create feed")
 		helper.RespondWithError(w, http.StatusInternalServerError, "Couldn't create feed")
 		return
 	}
 
-	// Automatically follow the newly created feed
+	// Automatically follow the new feed, listing the other feeds found on
+	// the page so the client can offer a different choice
+	cfg.followCreatedFeed(w, r, user, feed, candidates)
+}
+
+// createPrivateFeed creates a private feed whose requests are authenticated with the
+// submitted credentials, after checking that the feed can be fetched with them.
+func (cfg *ApiConfig) createPrivateFeed(w http.ResponseWriter, r *http.Request, user database.User, params models.Parameters, feedURL string) {
+	if cfg.Credentials == nil {
+		helper.RespondWithError(w, http.StatusNotImplemented, "Feed credentials are not supported")
+		return
+	}
+	if err := helper.ValidateCredentials(feedURL, params.Credentials); err != nil {
+		helper.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := cfg.Fetcher.FetchFeed(r.Context(), feedURL, "", "", params.Credentials); err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"func":    "createPrivateFeed",
+			"userID":  user.ID,
+			"feedURL": feedURL,
+		}).Warn("Couldn't fetch private feed")
+		var statusErr *helper.StatusError
+		if errors.As(err, &statusErr) &&
+			(statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden) {
+			helper.RespondWithError(w, http.StatusBadRequest, "Feed rejected the credentials")
+			return
+		}
+		if errors.Is(err, helper.ErrForbiddenAddress) {
+			helper.RespondWithError(w, http.StatusBadRequest, "Invalid feed URL")
+			return
+		}
+		helper.RespondWithError(w, http.StatusBadRequest, "Couldn't fetch feed")
+		return
+	}
+
+	// The credentials are bound to the feed ID, so it is chosen before encrypting
+	feedID := uuid.New()
+	sealed, err := cfg.Credentials.Encrypt(feedID, params.Credentials)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"func":   "createPrivateFeed",
+			"userID": user.ID,
+		}).Error("Couldn't encrypt feed credentials")
+		helper.RespondWithError(w, http.StatusInternalServerError, "Couldn't create feed")
+		return
+	}
+
+	feed, err := cfg.DB.CreateFeed(r.Context(), database.CreateFeedParams{
+		ID:               feedID,
+		CreatedAt:        time.Now().UTC(),
+		UpdatedAt:        time.Now().UTC(),
+		UserID:           user.ID,
+		Name:             params.Name,
+		Url:              feedURL,
+		FetchFullContent: params.FetchFullContent,
+		IsPrivate:        true,
+		Credentials:      sealed,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"func":     "createPrivateFeed",
+			"userID":   user.ID,
+			"feedName": params.Name,
+			"feedURL":  feedURL,
+		}).Error("Couldn't create feed")
+		helper.RespondWithError(w, http.StatusInternalServerError, "Couldn't create feed")
+		return
+	}
+
+	cfg.followCreatedFeed(w, r, user, feed, nil)
+}
+
+// followCreatedFeed automatically follows a newly created feed for its creator and
+// responds with the feed and feed follow details.
+func (cfg *ApiConfig) followCreatedFeed(w http.ResponseWriter, r *http.Request, user database.User, feed database.Feed, candidates []models.FeedCandidate) {
 	feedFollow, err := cfg.DB.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now().UTC(),
@@ -58,7 +184,7 @@ func (cfg *ApiConfig) HandlerFeedCreate(w http.ResponseWriter, r *http.Request,
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error":  err,
-			"func":   "HandlerFeedCreate",
+			"func":   "followCreatedFeed",
 			"userID": user.ID,
 			"feedID": feed.ID,
 		}).Error("Couldn't create feed follow")
@@ -66,17 +192,18 @@ func (cfg *ApiConfig) HandlerFeedCreate(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	// Respond with the created feed and feed follow details
 	helper.RespondWithJSON(w, http.StatusOK, struct {
-		Feed       models.Feed       `json:"feed"`
-		FeedFollow models.FeedFollow `json:"feed_follow"`
+		Feed       models.Feed            `json:"feed"`
+		FeedFollow models.FeedFollow      `json:"feed_follow"`
+		Candidates []models.FeedCandidate `json:"candidates,omitempty"`
 	}{
 		Feed:       models.DatabaseFeedToFeed(feed),
 		FeedFollow: models.DatabaseFeedFollowToFeedFollow(feedFollow),
+		Candidates: candidates,
 	})
 }
 
-// HandlerGetFeeds retrieves all feeds from the database.
+// HandlerGetFeeds retrieves all public feeds from the database.
 func (cfg *ApiConfig) HandlerGetFeeds(w http.ResponseWriter, r *http.Request) {
 	// Fetch all feeds from the database
 	feeds, err := cfg.DB.GetFeeds(r.Context())
@@ -92,3 +219,70 @@ func (cfg *ApiConfig) HandlerGetFeeds(w http.ResponseWriter, r *http.Request) {
 	// Respond with the retrieved feeds in JSON format
 	helper.RespondWithJSON(w, http.StatusOK, models.DatabaseFeedsToFeeds(feeds))
 }
+
+// HandlerFeedFetchesGet returns the most recent fetch attempts of a feed, newest first,
+// to help diagnose feeds that are not updating. An optional limit parameter caps the result.
+func (cfg *ApiConfig) HandlerFeedFetchesGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedIDStr := chi.URLParam(r, "feedID")
+	feedID, err := uuid.Parse(feedIDStr)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"func":      "HandlerFeedFetchesGet",
+			"feedIDStr": feedIDStr,
+		}).Error("Invalid feed ID")
+		helper.RespondWithError(w, http.StatusBadRequest, "Invalid feed ID")
+		return
+	}
+
+	// Default to the last 20 attempts, never more than the retained history
+	limit := 20
+	limitStr := r.URL.Query().Get("limit")
+	if specifiedLimit, err := strconv.Atoi(limitStr); err == nil && specifiedLimit > 0 {
+		limit = specifiedLimit
+		if limit > helper.FeedFetchRetention {
+			limit = helper.FeedFetchRetention
+		}
+	} else if limitStr != "" {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"func":     "HandlerFeedFetchesGet",
+			"limitStr": limitStr,
+		}).Warn("Invalid limit parameter, using default limit")
+	}
+
+	// Private feeds are only visible to their owner
+	feed, err := cfg.DB.GetFeedByID(r.Context(), feedID)
+	if err == nil && feed.IsPrivate && feed.UserID != user.ID {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			helper.RespondWithError(w, http.StatusNotFound, "Feed not found")
+			return
+		}
+		log.WithFields(log.Fields{
+			"error":  err,
+			"func":   "HandlerFeedFetchesGet",
+			"feedID": feedID,
+		}).Error("Couldn't get feed")
+		helper.RespondWithError(w, http.StatusInternalServerError, "Couldn't get feed")
+		return
+	}
+
+	fetches, err := cfg.DB.GetFeedFetches(r.Context(), database.GetFeedFetchesParams{
+		FeedID: feedID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"func":   "HandlerFeedFetchesGet",
+			"feedID": feedID,
+		}).Error("Couldn't get feed fetches")
+		helper.RespondWithError(w, http.StatusInternalServerError, "Couldn't get feed fetches")
+		return
+	}
+
+	helper.RespondWithJSON(w, http.StatusOK, models.DatabaseFeedFetchesToFeedFetches(fetches))
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -50,6 +52,25 @@ func (cfg *ApiConfig) HandlerFeedFollowCreate(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Private feeds can only be followed by their owner
+	feed, err := cfg.DB.GetFeedByID(r.Context(), params.FeedID)
+	if err == nil && feed.IsPrivate && feed.UserID != user.ID {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			helper.RespondWithError(w, http.StatusNotFound, "Feed not found")
+			return
+		}
+		log.WithFields(log.Fields{
+			"error":  err,
+			"func":   "HandlerFeedFollowCreate",
+			"feedID": params.FeedID,
+		}).Error("Couldn't get feed")
+		helper.RespondWithError(w, http.StatusInternalServerError, "Couldn't get feed")
+		return
+	}
+
 	// Create a new feed follow record in the database
 	feedFollow, err := cfg.DB.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
 		ID:        uuid.New(),
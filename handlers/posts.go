@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/google/uuid"
 	"github.com/qmranik/rss-aggregator-backend/helper"
 	"github.com/qmranik/rss-aggregator-backend/internal/database"
 	"github.com/qmranik/rss-aggregator-backend/models"
@@ -45,6 +46,42 @@ func (cfg *ApiConfig) HandlerPostsGet(w http.ResponseWriter, r *http.Request, us
 		return
 	}
 
+	// Posts stored before HTML was sanitized on ingest may not have been backfilled yet
+	for i, post := range posts {
+		posts[i] = helper.SanitizeStoredPost(post)
+	}
+
+	// Load the enclosures, media, artwork and tags of the returned posts
+	postIDs := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+	attachments, err := cfg.DB.GetAttachmentsForPosts(r.Context(), postIDs)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"func":   "HandlerPostsGet",
+			"userID": user.ID,
+		}).Error("Couldn't get attachments for posts")
+		helper.RespondWithError(w, http.StatusInternalServerError, "Couldn't get posts for user")
+		return
+	}
+
+	tags, err := cfg.DB.GetTagsForPosts(r.Context(), postIDs)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"func":   "HandlerPostsGet",
+			"userID": user.ID,
+		}).Error("Couldn't get tags for posts")
+		helper.RespondWithError(w, http.StatusInternalServerError, "Couldn't get posts for user")
+		return
+	}
+
+	result := models.DatabasePostsToPosts(posts)
+	models.AttachAttachments(result, attachments)
+	models.AttachTags(result, tags)
+
 	// Respond with the retrieved posts in JSON format
-	helper.RespondWithJSON(w, http.StatusOK, models.DatabasePostsToPosts(posts))
+	helper.RespondWithJSON(w, http.StatusOK, result)
 }
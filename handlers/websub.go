@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/qmranik/rss-aggregator-backend/helper"
+	"github.com/qmranik/rss-aggregator-backend/internal/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// HandlerWebSubVerify answers the verification of intent sent by a WebSub hub,
+// echoing the challenge for outstanding subscription requests and recording denials.
+func (cfg *ApiConfig) HandlerWebSubVerify(w http.ResponseWriter, r *http.Request) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "feedID"))
+	if err != nil {
+		helper.RespondWithError(w, http.StatusNotFound, "Unknown subscription")
+		return
+	}
+
+	query := r.URL.Query()
+	sub, err := cfg.DB.GetWebSubSubscription(r.Context(), feedID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"func":   "HandlerWebSubVerify",
+				"feedID": feedID,
+			}).Error("Couldn't get WebSub subscription")
+		}
+		helper.RespondWithError(w, http.StatusNotFound, "Unknown subscription")
+		return
+	}
+
+	// Only answer for the topic of a request we sent and that is still outstanding
+	if query.Get("hub.topic") != sub.Topic {
+		helper.RespondWithError(w, http.StatusNotFound, "Unknown topic")
+		return
+	}
+	if !helper.AwaitsVerification(sub, time.Now().UTC()) {
+		helper.RespondWithError(w, http.StatusNotFound, "No subscription request outstanding")
+		return
+	}
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		challenge := query.Get("hub.challenge")
+		if challenge == "" {
+			helper.RespondWithError(w, http.StatusBadRequest, "Missing challenge")
+			return
+		}
+
+		lease := helper.WebSubLease(query.Get("hub.lease_seconds"))
+		err := cfg.DB.ActivateWebSubSubscription(r.Context(), database.ActivateWebSubSubscriptionParams{
+			FeedID:         feedID,
+			LeaseExpiresAt: sql.NullTime{Time: time.Now().UTC().Add(lease), Valid: true},
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"func":   "HandlerWebSubVerify",
+				"feedID": feedID,
+			}).Error("Couldn't activate WebSub subscription")
+			helper.RespondWithError(w, http.StatusInternalServerError, "Couldn't activate subscription")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(challenge))
+
+	case "denied":
+		log.WithFields(log.Fields{
+			"feedID": feedID,
+			"hub":    sub.Hub,
+			"reason": query.Get("hub.reason"),
+		}).Warn("WebSub hub denied subscription")
+		err := cfg.DB.UpdateWebSubSubscriptionState(r.Context(), database.UpdateWebSubSubscriptionStateParams{
+			FeedID: feedID,
+			State:  helper.WebSubDenied,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"func":   "HandlerWebSubVerify",
+				"feedID": feedID,
+			}).Error("Couldn't record WebSub denial")
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		// We never unsubscribe, so any other request is not ours
+		helper.RespondWithError(w, http.StatusNotFound, "Unexpected mode")
+	}
+}
+
+// HandlerWebSubNotify receives content distributed by a WebSub hub and stores its
+// posts once the signature proves it was sent by the hub we subscribed to.
+func (cfg *ApiConfig) HandlerWebSubNotify(w http.ResponseWriter, r *http.Request) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "feedID"))
+	if err != nil {
+		helper.RespondWithError(w, http.StatusNotFound, "Unknown subscription")
+		return
+	}
+
+	sub, err := cfg.DB.GetWebSubSubscription(r.Context(), feedID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Tell the hub to stop sending content for this callback
+			helper.RespondWithError(w, http.StatusGone, "Unknown subscription")
+			return
+		}
+		log.WithFields(log.Fields{
+			"error":  err,
+			"func":   "HandlerWebSubNotify",
+			"feedID": feedID,
+		}).Error("Couldn't get WebSub subscription")
+		helper.RespondWithError(w, http.StatusInternalServerError, "Couldn't get subscription")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, helper.MaxPushBodySize+1))
+	if err != nil {
+		helper.RespondWithError(w, http.StatusBadRequest, "Couldn't read body")
+		return
+	}
+	if len(body) > helper.MaxPushBodySize {
+		helper.RespondWithError(w, http.StatusRequestEntityTooLarge, "Body too large")
+		return
+	}
+
+	// Acknowledge but ignore content that was not signed with our secret
+	if !helper.VerifyWebSubSignature(sub.Secret, body, r.Header.Get("X-Hub-Signature")) {
+		log.WithFields(log.Fields{
+			"func":   "HandlerWebSubNotify",
+			"feedID": feedID,
+		}).Warn("Ignoring WebSub content with an invalid signature")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	feed, err := cfg.DB.GetFeedByID(r.Context(), feedID)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"func":   "HandlerWebSubNotify",
+			"feedID": feedID,
+		}).Error("Couldn't get feed")
+		helper.RespondWithError(w, http.StatusInternalServerError, "Couldn't get feed")
+		return
+	}
+
+	feedData, err := helper.ParseFeedWithTimeout(body, r.Header.Get("Content-Type"), helper.FeedParseTimeout)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"func":   "HandlerWebSubNotify",
+			"feedID": feedID,
+		}).Warn("Couldn't parse WebSub content")
+		helper.RespondWithError(w, http.StatusBadRequest, "Couldn't parse feed")
+		return
+	}
+
+	cfg.Scraper.IngestFeed(r.Context(), feed, feedData)
+	w.WriteHeader(http.StatusAccepted)
+}
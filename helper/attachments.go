@@ -0,0 +1,148 @@
+package helper
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qmranik/rss-aggregator-backend/internal/database"
+	"github.com/qmranik/rss-aggregator-backend/models"
+)
+
+// Kinds of attachments stored with a post.
+const (
+	AttachmentEnclosure = "enclosure" // AttachmentEnclosure is a media file such as a podcast episode.
+	AttachmentMedia     = "media"     // AttachmentMedia is a Media RSS media:content element.
+	AttachmentThumbnail = "thumbnail" // AttachmentThumbnail is a preview image of the item.
+	AttachmentImage     = "image"     // AttachmentImage is the itunes:image artwork of the item.
+)
+
+// ItemAttachments collects the enclosures, media and artwork of a feed item as attachments of a post.
+// Attachments are identified by URL, the first occurrence of a URL wins. Relative URLs are resolved
+// against the item's link and attachments without an http(s) URL are dropped.
+func ItemAttachments(postID uuid.UUID, item models.RSSItem, now time.Time) []database.UpsertPostAttachmentParams {
+	var attachments []database.UpsertPostAttachmentParams
+	seen := make(map[string]bool)
+	base, _ := url.Parse(item.Link)
+	add := func(kind, rawURL string, fill func(*database.UpsertPostAttachmentParams)) {
+		if strings.TrimSpace(rawURL) == "" {
+			return
+		}
+		link, ok := safeURL(rawURL, base)
+		if !ok || !(strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://")) || seen[link] {
+			return
+		}
+		seen[link] = true
+		attachment := database.UpsertPostAttachmentParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			PostID:    postID,
+			Kind:      kind,
+			Url:       link,
+		}
+		fill(&attachment)
+		attachments = append(attachments, attachment)
+	}
+
+	// The iTunes episode metadata describes the enclosure
+	duration := parseDuration(item.ITunesDuration)
+	episode := parseInt32(item.ITunesEpisode)
+	season := parseInt32(item.ITunesSeason)
+	for _, enclosure := range item.Enclosures {
+		add(AttachmentEnclosure, enclosure.URL, func(a *database.UpsertPostAttachmentParams) {
+			a.MimeType = nullString(enclosure.Type)
+			a.Length = parseInt64(enclosure.Length)
+			a.DurationSeconds = duration
+			a.Episode = episode
+			a.Season = season
+		})
+	}
+
+	contents := append(append([]models.MediaContent{}, item.MediaContent...), item.MediaGroup.Content...)
+	thumbnails := append(append([]models.MediaThumbnail{}, item.MediaThumbnails...), item.MediaGroup.Thumbnails...)
+	for _, content := range contents {
+		add(AttachmentMedia, content.URL, func(a *database.UpsertPostAttachmentParams) {
+			a.MimeType = nullString(content.Type)
+			a.Length = parseInt64(content.FileSize)
+			a.DurationSeconds = parseDuration(content.Duration)
+		})
+		thumbnails = append(thumbnails, content.Thumbnails...)
+	}
+	for _, thumbnail := range thumbnails {
+		add(AttachmentThumbnail, thumbnail.URL, func(a *database.UpsertPostAttachmentParams) {
+			a.Width = parseInt32(thumbnail.Width)
+			a.Height = parseInt32(thumbnail.Height)
+		})
+	}
+
+	add(AttachmentImage, item.ITunesImage.Href, func(*database.UpsertPostAttachmentParams) {})
+
+	return attachments
+}
+
+// AttachmentsHash returns a hash of the attachments of an item, which changes whenever any of them
+// is added, removed or edited. Items without attachments have an empty hash.
+func AttachmentsHash(attachments []database.UpsertPostAttachmentParams) string {
+	if len(attachments) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, a := range attachments {
+		fmt.Fprintf(h, "%s\x00%s\x00%v\x00%v\x00%v\x00%v\x00%v\x00%v\x00%v\n",
+			a.Kind, a.Url, a.MimeType, a.Length, a.DurationSeconds, a.Episode, a.Season, a.Width, a.Height)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// parseDuration parses an itunes:duration given as seconds, MM:SS or HH:MM:SS.
+func parseDuration(value string) sql.NullInt32 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return sql.NullInt32{}
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return sql.NullInt32{}
+	}
+	var seconds int64
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return sql.NullInt32{}
+		}
+		seconds = seconds*60 + int64(n)
+	}
+	return sql.NullInt32{Int32: int32(seconds), Valid: true}
+}
+
+// parseInt32 parses a non-negative integer attribute, ignoring malformed values.
+func parseInt32(value string) sql.NullInt32 {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil || n < 0 {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: int32(n), Valid: true}
+}
+
+// parseInt64 parses a positive size in bytes, ignoring malformed and zero values.
+func parseInt64(value string) sql.NullInt64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n <= 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: n, Valid: true}
+}
+
+// nullString returns a NULL for empty strings.
+func nullString(value string) sql.NullString {
+	value = strings.TrimSpace(value)
+	return sql.NullString{String: value, Valid: value != ""}
+}
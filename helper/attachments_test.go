@@ -0,0 +1,41 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qmranik/rss-aggregator-backend/models"
+)
+
+func TestItemAttachmentsURLs(t *testing.T) {
+	item := models.RSSItem{
+		Link: "https://example.com/episodes/1",
+		Enclosures: []models.RSSEnclosure{
+			{URL: "audio/1.mp3", Type: "audio/mpeg"},
+			{URL: "javascript:alert(1)"},
+			{URL: " JavaScript:alert(1)"},
+			{URL: "data:audio/mpeg;base64,AAAA"},
+			{URL: "mailto:host@example.com"},
+			{URL: "https://example.com/episodes/audio/1.mp3"},
+		},
+		MediaContent: []models.MediaContent{
+			{URL: "//cdn.example.com/1.mp4", Thumbnails: []models.MediaThumbnail{{URL: "/thumb.jpg"}}},
+		},
+		ITunesImage: models.ITunesImage{Href: "ftp://example.com/art.jpg"},
+	}
+
+	var got []string
+	for _, attachment := range ItemAttachments(uuid.New(), item, time.Now()) {
+		got = append(got, attachment.Kind+" "+attachment.Url)
+	}
+	want := []string{
+		"enclosure https://example.com/episodes/audio/1.mp3",
+		"media https://cdn.example.com/1.mp4",
+		"thumbnail https://example.com/thumb.jpg",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("attachments = %q, want %q", got, want)
+	}
+}
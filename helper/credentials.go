@@ -0,0 +1,174 @@
+package helper
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/qmranik/rss-aggregator-backend/models"
+)
+
+var (
+	// ErrInvalidCredentials is returned for feed credentials that are incomplete or malformed.
+	ErrInvalidCredentials = errors.New("invalid feed credentials")
+	// ErrCredentialsUnavailable is returned when feed credentials are used without an encryption key.
+	ErrCredentialsUnavailable = errors.New("feed credentials are not configured")
+)
+
+// headerNamePattern matches valid HTTP header field names.
+var headerNamePattern = regexp.MustCompile("^[!#$%&'*+\\-.^_`|~0-9A-Za-z]+$")
+
+// reservedHeaders may not be replaced by a custom credential header.
+var reservedHeaders = map[string]bool{
+	"Host":              true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Accept-Encoding":   true,
+	"If-None-Match":     true,
+	"If-Modified-Since": true,
+	"User-Agent":        true,
+}
+
+// ValidateCredentials checks that feed credentials are complete for their type
+// and that the feed they are sent to is fetched over https.
+func ValidateCredentials(feedURL string, creds *models.FeedCredentials) error {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return fmt.Errorf("%w: invalid feed URL", ErrInvalidCredentials)
+	}
+	if err := requireHTTPS(u); err != nil {
+		return err
+	}
+
+	switch creds.Type {
+	case models.CredentialBasic:
+		if creds.Username == "" || strings.Contains(creds.Username, ":") {
+			return fmt.Errorf("%w: basic credentials need a username without colons", ErrInvalidCredentials)
+		}
+	case models.CredentialBearer:
+		if creds.Token == "" || strings.ContainsAny(creds.Token, "\r\n") {
+			return fmt.Errorf("%w: bearer credentials need a token", ErrInvalidCredentials)
+		}
+	case models.CredentialHeader:
+		name := http.CanonicalHeaderKey(creds.HeaderName)
+		if !headerNamePattern.MatchString(name) || reservedHeaders[name] {
+			return fmt.Errorf("%w: invalid header name", ErrInvalidCredentials)
+		}
+		if creds.HeaderValue == "" || strings.ContainsAny(creds.HeaderValue, "\r\n\x00") {
+			return fmt.Errorf("%w: invalid header value", ErrInvalidCredentials)
+		}
+	default:
+		return fmt.Errorf("%w: type must be basic, bearer or header", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+// requireHTTPS refuses to send credentials to a URL that is not fetched over https.
+func requireHTTPS(u *url.URL) error {
+	if !strings.EqualFold(u.Scheme, "https") {
+		return fmt.Errorf("%w: the feed URL must use https", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+// applyCredentials authenticates a feed request.
+func applyCredentials(req *http.Request, creds *models.FeedCredentials) {
+	switch creds.Type {
+	case models.CredentialBasic:
+		req.SetBasicAuth(creds.Username, creds.Password)
+	case models.CredentialBearer:
+		req.Header.Set("Authorization", "Bearer "+creds.Token)
+	case models.CredentialHeader:
+		req.Header.Set(creds.HeaderName, creds.HeaderValue)
+	}
+}
+
+// credentialClient returns a copy of client that strips credentials from redirects
+// to another host or to plain http, even on the same host. net/http only drops the
+// Authorization header, and only when the redirect leaves the domain, so custom
+// headers would otherwise leak and any credentials could be sent in cleartext.
+func credentialClient(client *http.Client, creds *models.FeedCredentials) *http.Client {
+	withCreds := *client
+	withCreds.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if req.URL.Host != via[0].URL.Host || requireHTTPS(req.URL) != nil {
+			req.Header.Del("Authorization")
+			if creds.Type == models.CredentialHeader {
+				req.Header.Del(creds.HeaderName)
+			}
+		}
+		return nil
+	}
+	return &withCreds
+}
+
+// CredentialCipher encrypts feed credentials at rest with AES-256-GCM. Each
+// ciphertext is bound to its feed, so it cannot be copied onto another feed.
+type CredentialCipher struct {
+	aead cipher.AEAD
+}
+
+// NewCredentialCipher creates a CredentialCipher from a base64 encoded 32 byte key.
+func NewCredentialCipher(encodedKey string) (*CredentialCipher, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil {
+		return nil, fmt.Errorf("decoding credentials key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("credentials key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &CredentialCipher{aead: aead}, nil
+}
+
+// Encrypt seals the credentials of a feed, prefixing the ciphertext with its nonce.
+func (c *CredentialCipher) Encrypt(feedID uuid.UUID, creds *models.FeedCredentials) ([]byte, error) {
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, feedID[:]), nil
+}
+
+// Decrypt opens the credentials of a feed sealed by Encrypt.
+func (c *CredentialCipher) Decrypt(feedID uuid.UUID, sealed []byte) (*models.FeedCredentials, error) {
+	if len(sealed) < c.aead.NonceSize() {
+		return nil, errors.New("feed credentials ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, feedID[:])
+	if err != nil {
+		return nil, fmt.Errorf("decrypting feed credentials: %w", err)
+	}
+
+	var creds models.FeedCredentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
@@ -0,0 +1,84 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/qmranik/rss-aggregator-backend/models"
+)
+
+func TestValidateCredentials(t *testing.T) {
+	bearer := &models.FeedCredentials{Type: models.CredentialBearer, Token: "secret"}
+
+	tests := []struct {
+		name    string
+		feedURL string
+		creds   *models.FeedCredentials
+		wantErr bool
+	}{
+		{name: "bearer over https", feedURL: "https://example.com/feed", creds: bearer},
+		{name: "bearer over http", feedURL: "http://example.com/feed", creds: bearer, wantErr: true},
+		{name: "basic", feedURL: "https://example.com/feed", creds: &models.FeedCredentials{Type: models.CredentialBasic, Username: "me", Password: "pw"}},
+		{name: "basic username with colon", feedURL: "https://example.com/feed", creds: &models.FeedCredentials{Type: models.CredentialBasic, Username: "me:you"}, wantErr: true},
+		{name: "custom header", feedURL: "https://example.com/feed", creds: &models.FeedCredentials{Type: models.CredentialHeader, HeaderName: "X-Api-Key", HeaderValue: "k"}},
+		{name: "reserved header", feedURL: "https://example.com/feed", creds: &models.FeedCredentials{Type: models.CredentialHeader, HeaderName: "Host", HeaderValue: "k"}, wantErr: true},
+		{name: "unknown type", feedURL: "https://example.com/feed", creds: &models.FeedCredentials{Type: "cookie"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCredentials(tt.feedURL, tt.creds)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateCredentials() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("ValidateCredentials() error = %v, want %v", err, ErrInvalidCredentials)
+			}
+		})
+	}
+}
+
+func TestCredentialClientRedirects(t *testing.T) {
+	creds := &models.FeedCredentials{Type: models.CredentialHeader, HeaderName: "X-Api-Key", HeaderValue: "k"}
+	client := credentialClient(&http.Client{}, creds)
+
+	tests := []struct {
+		name      string
+		target    string
+		wantCreds bool
+	}{
+		{name: "same host over https", target: "https://example.com/new-feed", wantCreds: true},
+		{name: "downgrade to http on the same host", target: "http://example.com/new-feed"},
+		{name: "other host", target: "https://other.example.com/feed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, _ := http.NewRequest(http.MethodGet, "https://example.com/feed", nil)
+			req, _ := http.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set("Authorization", "Bearer k")
+			req.Header.Set("X-Api-Key", "k")
+
+			if err := client.CheckRedirect(req, []*http.Request{first}); err != nil {
+				t.Fatalf("CheckRedirect() error = %v", err)
+			}
+			gotCreds := req.Header.Get("Authorization") != "" || req.Header.Get("X-Api-Key") != ""
+			if gotCreds != tt.wantCreds {
+				t.Errorf("credentials kept = %v, want %v", gotCreds, tt.wantCreds)
+			}
+		})
+	}
+}
+
+func TestFetchFeedRefusesCredentialsOverHTTP(t *testing.T) {
+	fetcher := &HTTPFetcher{Client: &http.Client{}}
+	creds := &models.FeedCredentials{Type: models.CredentialBearer, Token: "secret"}
+
+	// The request is refused before any connection is made
+	_, err := fetcher.FetchFeed(context.Background(), "http://127.0.0.1:1/feed", "", "", creds)
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("FetchFeed() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+}
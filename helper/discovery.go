@@ -0,0 +1,200 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/qmranik/rss-aggregator-backend/models"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+)
+
+// ErrNoFeedFound is returned when no feed can be discovered from a URL.
+var ErrNoFeedFound = errors.New("no feed found")
+
+// maxDiscoveryBodySize bounds how much of a web page is read when looking for its icon.
+// Pages given for feed discovery may be feeds themselves and are read up to maxFeedBodySize.
+const maxDiscoveryBodySize = 2 << 20
+
+// feedLinkTypes are the <link type> values advertising a feed. Generic JSON is left out,
+// sites use it to advertise their APIs, such as WordPress' /wp-json/ endpoints.
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/rdf+xml":   true,
+	"application/feed+json": true,
+}
+
+// commonFeedPaths are probed when a page does not advertise any feed.
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss",
+	"/rss.xml",
+	"/feed.xml",
+	"/atom.xml",
+	"/index.xml",
+	"/feed.json",
+}
+
+// DiscoverFeed resolves a user-supplied URL to a feed URL. A URL that already serves
+// a feed is returned unchanged. For a web page the advertised <link rel="alternate">
+// feeds are collected, falling back to common feed paths on the same site, and the
+// first candidate that parses as a feed is returned together with all candidates found.
+func (f *HTTPFetcher) DiscoverFeed(ctx context.Context, pageURL string) (string, []models.FeedCandidate, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"pageURL": pageURL,
+			"error":   err,
+		}).Error("Failed to fetch page for feed discovery")
+		return "", nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return "", nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	// Closing the page releases its host slot before candidates on the same site are probed
+	dat, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBodySize))
+	resp.Body.Close()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The URL already points at a feed
+	if _, err := ParseFeedWithTimeout(dat, resp.Header.Get("Content-Type"), FeedParseTimeout); err == nil {
+		return pageURL, nil, nil
+	}
+
+	// Resolve relative links against the final URL after redirects
+	if resp.Request != nil && resp.Request.URL != nil {
+		base = resp.Request.URL
+	}
+
+	candidates := feedLinks(dat, base)
+	guessed := len(candidates) == 0
+	if guessed {
+		for _, path := range commonFeedPaths {
+			candidates = append(candidates, models.FeedCandidate{
+				URL: base.ResolveReference(&url.URL{Path: path}).String(),
+			})
+		}
+	}
+
+	// Pick the first candidate that actually serves a feed
+	var found []models.FeedCandidate
+	for _, candidate := range candidates {
+		if _, err := f.FetchFeed(ctx, candidate.URL, "", "", nil); err != nil {
+			continue
+		}
+		found = append(found, candidate)
+
+		// Guessed paths usually alias the same feed, one is enough
+		if guessed {
+			break
+		}
+	}
+	if len(found) == 0 {
+		return "", nil, ErrNoFeedFound
+	}
+
+	return found[0].URL, found, nil
+}
+
+// feedLinks extracts the feeds advertised by <link rel="alternate"> elements of an HTML page.
+func feedLinks(dat []byte, base *url.URL) []models.FeedCandidate {
+	var candidates []models.FeedCandidate
+	seen := make(map[string]bool)
+
+	htmlLinks(dat, base, func(token html.Token, href string) {
+		if !hasToken(attr(token, "rel"), "alternate") {
+			return
+		}
+		linkType := strings.ToLower(strings.TrimSpace(attr(token, "type")))
+		if !feedLinkTypes[linkType] || seen[href] {
+			return
+		}
+		seen[href] = true
+		candidates = append(candidates, models.FeedCandidate{
+			URL:   href,
+			Title: attr(token, "title"),
+			Type:  linkType,
+		})
+	})
+	return candidates
+}
+
+// htmlLinks calls fn, in document order, for each <link> element of an HTML page whose
+// href resolves to a publicly reachable http(s) URL, passing the resolved URL.
+// Relative links are resolved against base, or the page's <base href> once it is seen.
+func htmlLinks(dat []byte, base *url.URL, fn func(token html.Token, href string)) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(dat))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		switch token.Data {
+		case "base":
+			// <base href> changes how relative links resolve
+			if href := attr(token, "href"); href != "" {
+				if resolved, err := base.Parse(href); err == nil {
+					base = resolved
+				}
+			}
+
+		case "link":
+			href := strings.TrimSpace(attr(token, "href"))
+			if href == "" {
+				continue
+			}
+			resolved, err := base.Parse(href)
+			if err != nil {
+				continue
+			}
+			if _, err := ValidateFeedURL(resolved.String()); err != nil {
+				continue
+			}
+			fn(token, resolved.String())
+		}
+	}
+}
+
+// attr returns the value of an attribute of an HTML token.
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasToken reports whether a space separated attribute value contains a token.
+func hasToken(value, token string) bool {
+	for _, field := range strings.Fields(value) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
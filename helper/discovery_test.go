@@ -0,0 +1,119 @@
+package helper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qmranik/rss-aggregator-backend/models"
+)
+
+const linksPage = `<html><head>
+<base href="https://cdn.example.com/site/">
+<link rel="alternate" type="application/rss+xml" title="Posts" href="feed.xml">
+<link rel="Alternate" type="application/atom+xml" href="https://cdn.example.com/site/feed.xml">
+<link rel="alternate" type="text/html" href="/fr/">
+<link rel="alternate" type="application/feed+json" href="http://127.0.0.1/feed.json">
+<link rel="alternate" type="application/json" href="https://example.com/wp-json/wp/v2/pages/1">
+<link rel="apple-touch-icon" href="/touch.png">
+<link rel="shortcut icon" href="favicon.png">
+<link rel="icon" href="">
+</head></html>`
+
+func TestFeedLinks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/")
+	want := []models.FeedCandidate{
+		{URL: "https://cdn.example.com/site/feed.xml", Title: "Posts", Type: "application/rss+xml"},
+	}
+	if got := feedLinks([]byte(linksPage), base); !reflect.DeepEqual(got, want) {
+		t.Errorf("feedLinks() = %+v, want %+v", got, want)
+	}
+}
+
+func TestIconLink(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/")
+	if got, want := iconLink([]byte(linksPage), base), "https://cdn.example.com/site/favicon.png"; got != want {
+		t.Errorf("iconLink() = %q, want %q", got, want)
+	}
+}
+
+// newSingleSlotFetcher returns a fetcher allowing a single request per host at a time,
+// which gives up quickly when the host is busy.
+func newSingleSlotFetcher() *HTTPFetcher {
+	return &HTTPFetcher{Client: &http.Client{Transport: &politeTransport{
+		base:    http.DefaultTransport,
+		limiter: NewHostLimiter(1, 0),
+		timeout: time.Second,
+		maxWait: 200 * time.Millisecond,
+	}}}
+}
+
+func TestDiscoverFeedProbesSameHost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>No feed links</title></head></html>`))
+		case "/feed":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte(`<rss version="2.0"><channel><title>Feed</title></channel></rss>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	// The page must not hold the only slot of its host while the guessed paths are probed
+	feedURL, _, err := newSingleSlotFetcher().DiscoverFeed(context.Background(), srv.URL+"/")
+	if err != nil {
+		t.Fatalf("DiscoverFeed() error = %v", err)
+	}
+	if feedURL != srv.URL+"/feed" {
+		t.Errorf("DiscoverFeed() = %q, want %q", feedURL, srv.URL+"/feed")
+	}
+}
+
+func TestDiscoverFeedLargeFeed(t *testing.T) {
+	body := `<rss version="2.0"><channel><title>Large</title><item><title>Big</title><description>` +
+		strings.Repeat("x", 3<<20) + `</description></item></channel></rss>`
+	srv := serveFeed(t, body)
+
+	// A feed larger than the pages read for icons is still recognized as a feed
+	feedURL, _, err := newSingleSlotFetcher().DiscoverFeed(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("DiscoverFeed() error = %v", err)
+	}
+	if feedURL != srv.URL {
+		t.Errorf("DiscoverFeed() = %q, want the feed URL %q", feedURL, srv.URL)
+	}
+}
+
+func TestFindIconFavicon(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>No icon links</title></head></html>`))
+		case "/favicon.ico":
+			w.Header().Set("Content-Type", "image/x-icon")
+			w.Write([]byte{0, 0, 1, 0})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	// The page must not hold the only slot of its host while /favicon.ico is checked
+	icon, err := newSingleSlotFetcher().FindIcon(context.Background(), srv.URL+"/")
+	if err != nil {
+		t.Fatalf("FindIcon() error = %v", err)
+	}
+	if icon != srv.URL+"/favicon.ico" {
+		t.Errorf("FindIcon() = %q, want %q", icon, srv.URL+"/favicon.ico")
+	}
+}
@@ -0,0 +1,262 @@
+package helper
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/qmranik/rss-aggregator-backend/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxFeedBodySize bounds the size of a feed document after decompression.
+const maxFeedBodySize = 10 << 20
+
+// ErrFeedTooLarge is returned when a feed document exceeds maxFeedBodySize.
+var ErrFeedTooLarge = errors.New("feed document too large")
+
+// Fetcher retrieves and parses the feed document at a URL.
+type Fetcher interface {
+	FetchFeed(ctx context.Context, feedURL, etag, lastModified string, creds *models.FeedCredentials) (*FetchResult, error)
+}
+
+// FetchResult holds the outcome of a feed fetch.
+type FetchResult struct {
+	Feed         *models.RSSFeed // Feed is nil when the server reports the feed as not modified.
+	NotModified  bool            // NotModified is true when the server answered 304 Not Modified.
+	ETag         string          // ETag is the validator returned by the server, if any.
+	LastModified string          // LastModified is the Last-Modified header returned by the server, if any.
+	CacheMaxAge  time.Duration   // CacheMaxAge is the Cache-Control max-age of the response, if any.
+	Hubs         []string        // Hubs are the WebSub hubs advertised by the feed, if any.
+	Topic        string          // Topic is the self URL of the feed used to subscribe to its hubs.
+	PermanentURL string          // PermanentURL is the new URL of a feed that moved permanently, if any.
+	StatusCode   int             // StatusCode is the HTTP status code of the response.
+	Bytes        int64           // Bytes is the size of the decompressed feed document.
+}
+
+// StatusError is returned by FetchFeed when the server answers with an unexpected status.
+type StatusError struct {
+	StatusCode int           // StatusCode is the HTTP status code of the response.
+	RetryAfter time.Duration // RetryAfter is the delay requested by a Retry-After header, if any.
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status fetching feed: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// HTTPFetcher fetches feeds over HTTP.
+type HTTPFetcher struct {
+	Client *http.Client // Client performs the feed requests.
+}
+
+// NewHTTPFetcher creates an HTTPFetcher whose requests time out DefaultRequestTimeout after
+// their host is free, identify themselves with userAgent, are rate limited per host by limiter,
+// only fetch URLs allowed by the host's robots.txt and never connect to internal addresses.
+// Requests waiting longer than DefaultMaxHostWait for their host fail with ErrHostBusy.
+func NewHTTPFetcher(userAgent string, limiter *HostLimiter) *HTTPFetcher {
+	return NewHTTPFetcherWithTransport(NewSafeTransport(), userAgent, limiter)
+}
+
+// NewHTTPFetcherWithTransport creates an HTTPFetcher like NewHTTPFetcher that sends its
+// requests through base instead of the transport refusing internal addresses.
+func NewHTTPFetcherWithTransport(base http.RoundTripper, userAgent string, limiter *HostLimiter) *HTTPFetcher {
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+
+	polite := &politeTransport{
+		base:      base,
+		limiter:   limiter,
+		userAgent: userAgent,
+		timeout:   DefaultRequestTimeout,
+		maxWait:   DefaultMaxHostWait,
+	}
+	robots := NewRobotsChecker(&http.Client{Transport: polite}, userAgent, limiter)
+
+	return &HTTPFetcher{
+		Client: &http.Client{
+			Transport: &robotsTransport{
+				next:    polite,
+				checker: robots,
+			},
+		},
+	}
+}
+
+// FetchFeed retrieves and parses an RSS, Atom or JSON feed from the specified URL.
+// The etag and lastModified validators from a previous fetch are sent as a conditional
+// request; a 304 response yields a result with NotModified set and no feed.
+// The credentials of a private feed, if given, authenticate the request.
+func (f *HTTPFetcher) FetchFeed(ctx context.Context, feedURL, etag, lastModified string, creds *models.FeedCredentials) (*FetchResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedURL": feedURL,
+			"error":   err,
+		}).Error("Failed to build feed request")
+		return nil, err
+	}
+	// Request compression explicitly, which disables the transport's transparent gzip handling
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	client := f.Client
+	if creds != nil {
+		// Never send credentials in cleartext, even for a feed that moved to plain http
+		if err := requireHTTPS(req.URL); err != nil {
+			log.WithFields(log.Fields{
+				"feedURL": feedURL,
+				"error":   err,
+			}).Error("Refusing to send feed credentials")
+			return nil, err
+		}
+		applyCredentials(req, creds)
+		client = credentialClient(f.Client, creds)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedURL": feedURL,
+			"error":   err,
+		}).Error("Failed to fetch feed")
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// The feed is unchanged since the last fetch, keep the stored validators
+	if resp.StatusCode == http.StatusNotModified {
+		return &FetchResult{
+			NotModified:  true,
+			ETag:         etag,
+			LastModified: lastModified,
+			CacheMaxAge:  cacheMaxAge(resp.Header.Get("Cache-Control")),
+			PermanentURL: permanentURL(resp),
+			StatusCode:   resp.StatusCode,
+		}, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.WithFields(log.Fields{
+			"feedURL": feedURL,
+			"status":  resp.StatusCode,
+		}).Error("Unexpected status fetching feed")
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	body, err := decompressBody(resp)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedURL":  feedURL,
+			"encoding": resp.Header.Get("Content-Encoding"),
+			"error":    err,
+		}).Error("Failed to decompress response body")
+		return nil, err
+	}
+	defer body.Close()
+
+	// Read one byte past the limit to tell a complete document from a truncated one
+	dat, err := io.ReadAll(io.LimitReader(body, maxFeedBodySize+1))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedURL": feedURL,
+			"error":   err,
+		}).Error("Failed to read response body")
+		return nil, err
+	}
+	if len(dat) > maxFeedBodySize {
+		log.WithFields(log.Fields{
+			"feedURL": feedURL,
+			"limit":   maxFeedBodySize,
+		}).Error("Feed document too large")
+		return nil, ErrFeedTooLarge
+	}
+
+	rssFeed, err := ParseFeedWithTimeout(dat, resp.Header.Get("Content-Type"), FeedParseTimeout)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedURL": feedURL,
+			"error":   err,
+		}).Error("Failed to parse feed")
+		return nil, err
+	}
+
+	hubs, topic := webSubLinks(resp.Header, resp.Request.URL.String(), &rssFeed.Channel)
+
+	return &FetchResult{
+		Feed:         rssFeed,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		CacheMaxAge:  cacheMaxAge(resp.Header.Get("Cache-Control")),
+		Hubs:         hubs,
+		Topic:        topic,
+		PermanentURL: permanentURL(resp),
+		StatusCode:   resp.StatusCode,
+		Bytes:        int64(len(dat)),
+	}, nil
+}
+
+// permanentURL returns the URL reached by following only the permanent redirects
+// (301 and 308) at the start of the redirect chain of a response, or "" if there are none.
+func permanentURL(resp *http.Response) string {
+	// Walk back from the final request to the original one
+	var chain []*http.Request
+	for req := resp.Request; req != nil; req = req.Response.Request {
+		chain = append(chain, req)
+		if req.Response == nil {
+			break
+		}
+	}
+
+	var permanent string
+	for i := len(chain) - 2; i >= 0; i-- {
+		switch chain[i].Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			permanent = chain[i].URL.String()
+		default:
+			return permanent
+		}
+	}
+	return permanent
+}
+
+// decompressBody wraps the response body according to its Content-Encoding.
+func decompressBody(resp *http.Response) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(resp.Body)
+
+	case "deflate":
+		// "deflate" should be zlib-wrapped, but some servers send raw DEFLATE data
+		buffered := bufio.NewReader(resp.Body)
+		header, err := buffered.Peek(2)
+		if err == nil && isZlibHeader(header) {
+			return zlib.NewReader(buffered)
+		}
+		return flate.NewReader(buffered), nil
+
+	default:
+		return io.NopCloser(resp.Body), nil
+	}
+}
+
+// isZlibHeader reports whether the first two bytes of a stream form a zlib header.
+func isZlibHeader(header []byte) bool {
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
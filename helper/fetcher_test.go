@@ -0,0 +1,128 @@
+package helper
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const compressedFeed = `<rss version="2.0"><channel><title>Compressed</title><item><title>Post</title></item></channel></rss>`
+
+// compress encodes s with the writer returned by newWriter.
+func compress(t *testing.T, s string, newWriter func(io.Writer) (io.WriteCloser, error)) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, s); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFetchFeedDecompresses(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{name: "identity", body: []byte(compressedFeed)},
+		{
+			name:     "gzip",
+			encoding: "gzip",
+			body: compress(t, compressedFeed, func(w io.Writer) (io.WriteCloser, error) {
+				return gzip.NewWriter(w), nil
+			}),
+		},
+		{
+			name:     "x-gzip",
+			encoding: "X-Gzip",
+			body: compress(t, compressedFeed, func(w io.Writer) (io.WriteCloser, error) {
+				return gzip.NewWriter(w), nil
+			}),
+		},
+		{
+			name:     "zlib-wrapped deflate",
+			encoding: "deflate",
+			body: compress(t, compressedFeed, func(w io.Writer) (io.WriteCloser, error) {
+				return zlib.NewWriter(w), nil
+			}),
+		},
+		{
+			name:     "raw deflate",
+			encoding: "deflate",
+			body: compress(t, compressedFeed, func(w io.Writer) (io.WriteCloser, error) {
+				return flate.NewWriter(w, flate.DefaultCompression)
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/robots.txt" {
+					http.NotFound(w, r)
+					return
+				}
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Header().Set("Content-Type", "application/rss+xml")
+				w.Write(tt.body)
+			}))
+			defer srv.Close()
+
+			fetcher := NewHTTPFetcherWithTransport(http.DefaultTransport, "", NewHostLimiter(2, 0))
+			result, err := fetcher.FetchFeed(context.Background(), srv.URL+"/feed.xml", "", "", nil)
+			if err != nil {
+				t.Fatalf("FetchFeed() error = %v", err)
+			}
+			if result.Feed.Channel.Title != "Compressed" || len(result.Feed.Channel.Item) != 1 {
+				t.Errorf("FetchFeed() feed = %+v, want the decompressed feed", result.Feed.Channel)
+			}
+			if result.Bytes != int64(len(compressedFeed)) {
+				t.Errorf("FetchFeed() bytes = %d, want %d", result.Bytes, len(compressedFeed))
+			}
+		})
+	}
+}
+
+func TestFetchFeedCorruptGzip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write([]byte(compressedFeed))
+	}))
+	defer srv.Close()
+
+	fetcher := NewHTTPFetcherWithTransport(http.DefaultTransport, "", NewHostLimiter(2, 0))
+	if _, err := fetcher.FetchFeed(context.Background(), srv.URL+"/feed.xml", "", "", nil); err == nil {
+		t.Error("FetchFeed() error = nil, want an error for a body that is not gzip")
+	}
+}
+
+func TestIsZlibHeader(t *testing.T) {
+	zlibBody := compress(t, compressedFeed, func(w io.Writer) (io.WriteCloser, error) {
+		return zlib.NewWriter(w), nil
+	})
+	if !isZlibHeader(zlibBody[:2]) {
+		t.Errorf("isZlibHeader(%x) = false, want true", zlibBody[:2])
+	}
+	if isZlibHeader([]byte("<r")) {
+		t.Error(`isZlibHeader("<r") = true, want false`)
+	}
+}
@@ -0,0 +1,130 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+)
+
+// ErrNoIconFound is returned when a site advertises no icon and has no /favicon.ico.
+var ErrNoIconFound = errors.New("no icon found")
+
+// iconRels are the <link rel> values advertising a site icon, most preferred first.
+var iconRels = []string{"icon", "apple-touch-icon", "apple-touch-icon-precomposed"}
+
+// IconFinder discovers the icon of a web site.
+type IconFinder interface {
+	FindIcon(ctx context.Context, siteURL string) (string, error)
+}
+
+// FindIcon discovers the icon of the site at siteURL. The icons advertised by
+// <link rel="icon"> elements of the page are preferred, falling back to /favicon.ico
+// at the root of the site when it exists.
+func (f *HTTPFetcher) FindIcon(ctx context.Context, siteURL string) (string, error) {
+	base, err := url.Parse(siteURL)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, siteURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"siteURL": siteURL,
+			"error":   err,
+		}).Warn("Failed to fetch page for icon discovery")
+		return "", err
+	}
+
+	// Resolve relative links against the final URL after redirects
+	if resp.Request != nil && resp.Request.URL != nil {
+		base = resp.Request.URL
+	}
+
+	// Closing the page releases its host slot before /favicon.ico is requested from the same site
+	var dat []byte
+	if resp.StatusCode == http.StatusOK {
+		dat, err = io.ReadAll(io.LimitReader(resp.Body, maxDiscoveryBodySize))
+	}
+	resp.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	if icon := iconLink(dat, base); icon != "" {
+		return icon, nil
+	}
+
+	// Most sites still serve a favicon from the conventional location
+	favicon := base.ResolveReference(&url.URL{Path: "/favicon.ico"}).String()
+	if f.exists(ctx, favicon) {
+		return favicon, nil
+	}
+	return "", ErrNoIconFound
+}
+
+// exists reports whether a GET request for resourceURL succeeds.
+func (f *HTTPFetcher) exists(ctx context.Context, resourceURL string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resourceURL, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	return resp.StatusCode == http.StatusOK
+}
+
+// iconLink returns the most preferred icon advertised by <link> elements of an HTML page.
+func iconLink(dat []byte, base *url.URL) string {
+	icons := make(map[string]string)
+	htmlLinks(dat, base, func(token html.Token, href string) {
+		// "shortcut icon" is the legacy spelling of "icon"
+		for _, rel := range iconRels {
+			if hasToken(attr(token, "rel"), rel) && icons[rel] == "" {
+				icons[rel] = href
+			}
+		}
+	})
+
+	for _, rel := range iconRels {
+		if icon := icons[rel]; icon != "" {
+			return icon
+		}
+	}
+	return ""
+}
+
+// absoluteURL resolves ref against base, returning "" unless the result is a
+// publicly reachable http(s) URL that is safe to hand to clients.
+func absoluteURL(base, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	resolved, err := baseURL.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	valid, err := ValidateFeedURL(resolved.String())
+	if err != nil {
+		return ""
+	}
+	return valid
+}
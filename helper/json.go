@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/araddon/dateparse"
 	log "github.com/sirupsen/logrus"
@@ -59,9 +60,10 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Write(dat)
 }
 
-// ParsePubDate parses a pubDate string into a sql.NullTime, returning an error if parsing fails.
+// ParsePubDate parses a pubDate string into a sql.NullTime in UTC, returning an error if parsing fails.
+// Dates without a time zone are taken to be in UTC.
 func ParsePubDate(pubDate string) (sql.NullTime, error) {
-	t, err := dateparse.ParseAny(pubDate)
+	t, err := dateparse.ParseIn(pubDate, time.UTC)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"pubDate": pubDate,
@@ -72,7 +74,7 @@ func ParsePubDate(pubDate string) (sql.NullTime, error) {
 
 	// Return the parsed time as sql.NullTime
 	return sql.NullTime{
-		Time:  t,
+		Time:  t.UTC(),
 		Valid: true,
 	}, nil
 }
@@ -0,0 +1,116 @@
+package helper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/qmranik/rss-aggregator-backend/models"
+)
+
+// Sources of a post's publication date.
+const (
+	DateSourcePubDate   = "pub_date"   // DateSourcePubDate is the RSS pubDate element.
+	DateSourcePublished = "published"  // DateSourcePublished is the Atom published or JSON Feed date_published field.
+	DateSourceDCDate    = "dc_date"    // DateSourceDCDate is the Dublin Core dc:date element.
+	DateSourceUpdated   = "updated"    // DateSourceUpdated is the Atom updated or JSON Feed date_modified field.
+	DateSourceFirstSeen = "first_seen" // DateSourceFirstSeen is the time the item was first collected.
+)
+
+// maxTagLength is the longest category name kept as a tag.
+const maxTagLength = 100
+
+// rssAuthorPattern matches the RSS 2.0 author form "email (Name)".
+var rssAuthorPattern = regexp.MustCompile(`^\S+@\S+\s*\((.+)\)$`)
+
+// ItemAuthor returns the display name of an item's authors, preferring dc:creator over
+// the RSS author element, which usually holds an email address.
+func ItemAuthor(item models.RSSItem) string {
+	var names []string
+	for _, creator := range item.Creators {
+		if name := PlainText(creator); name != "" && !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		return strings.Join(names, ", ")
+	}
+
+	author := PlainText(item.Author)
+	if match := rssAuthorPattern.FindStringSubmatch(author); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+	return author
+}
+
+// ItemCategories returns the normalized, de-duplicated tag names of an item's categories.
+func ItemCategories(item models.RSSItem) []string {
+	var tags []string
+	for _, category := range item.Categories {
+		tag := strings.ToLower(PlainText(category))
+		if tag == "" || len(tag) > maxTagLength || contains(tags, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// TagsHash returns a hash of the tag names of an item, which changes whenever any of them
+// is added or removed. Items without tags have an empty hash.
+func TagsHash(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(strings.Join(tags, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ItemCommentsURL returns the absolute http(s) URL of an item's comments page, if any.
+func ItemCommentsURL(item models.RSSItem) string {
+	if strings.TrimSpace(item.Comments) == "" {
+		return ""
+	}
+	base, _ := url.Parse(item.Link)
+	comments, ok := safeURL(item.Comments, base)
+	if !ok || strings.HasPrefix(comments, "mailto:") {
+		return ""
+	}
+	return comments
+}
+
+// ItemPublishedAt returns the publication date of an item in UTC and the field it was
+// taken from. The pubDate, published, dc:date and updated fields are tried in turn,
+// falling back to firstSeen when none of them holds a valid date.
+func ItemPublishedAt(item models.RSSItem, firstSeen time.Time) (time.Time, string) {
+	if t, source, ok := itemDate(item); ok {
+		return t, source
+	}
+	return firstSeen.UTC(), DateSourceFirstSeen
+}
+
+// itemDate returns the first valid date of an item and the field it was taken from.
+func itemDate(item models.RSSItem) (time.Time, string, bool) {
+	candidates := []struct {
+		value  string
+		source string
+	}{
+		{item.PubDate, DateSourcePubDate},
+		{item.Published, DateSourcePublished},
+		{item.DCDate, DateSourceDCDate},
+		{item.Updated, DateSourceUpdated},
+	}
+	for _, candidate := range candidates {
+		value := strings.TrimSpace(candidate.value)
+		if value == "" {
+			continue
+		}
+		if t, err := ParsePubDate(value); err == nil {
+			return t.Time, candidate.source, true
+		}
+	}
+	return time.Time{}, "", false
+}
@@ -0,0 +1,201 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/qmranik/rss-aggregator-backend/models"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html/charset"
+)
+
+// ErrUnsupportedFeedFormat is returned when a document is not a recognised feed format.
+var ErrUnsupportedFeedFormat = errors.New("unsupported feed format")
+
+// ErrParseTimeout is returned when parsing a feed document takes longer than allowed.
+var ErrParseTimeout = errors.New("parsing feed timed out")
+
+// FeedParseTimeout bounds the time spent parsing a single feed document.
+const FeedParseTimeout = 5 * time.Second
+
+// jsonFeedVersionPrefix starts the version URL of every JSON Feed document.
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+// utf8BOM is the byte order mark some publishers prepend to UTF-8 documents.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// ParseFeedWithTimeout parses a feed document like ParseFeed, giving up with
+// ErrParseTimeout when parsing takes longer than timeout. The abandoned parse runs
+// to completion in the background, which bounded document sizes keep cheap.
+func ParseFeedWithTimeout(dat []byte, contentType string, timeout time.Duration) (*models.RSSFeed, error) {
+	type parseResult struct {
+		feed *models.RSSFeed
+		err  error
+	}
+	done := make(chan parseResult, 1)
+	go func() {
+		feed, err := ParseFeed(dat, contentType)
+		done <- parseResult{feed, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return result.feed, result.err
+	case <-timer.C:
+		return nil, ErrParseTimeout
+	}
+}
+
+// ParseFeed detects the format of a feed document and parses it into an RSSFeed.
+// RSS 2.0, RSS 1.0 (RDF), Atom 1.0 and JSON Feed documents are supported. The format is selected
+// from the Content-Type header when it is conclusive, otherwise the document is sniffed.
+// Documents are decoded to UTF-8 from the Content-Type charset, or from the XML declaration
+// when the header does not name one.
+func ParseFeed(dat []byte, contentType string) (*models.RSSFeed, error) {
+	dat, transcoded := transcodeToUTF8(dat, contentType)
+	dat = bytes.TrimPrefix(dat, utf8BOM)
+
+	if isJSONFeed(dat, contentType) {
+		var jsonFeed models.JSONFeed
+		if err := json.Unmarshal(dat, &jsonFeed); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("Failed to unmarshal JSON feed")
+			return nil, err
+		}
+		// Other JSON documents, such as API responses, are not feeds
+		if !strings.HasPrefix(jsonFeed.Version, jsonFeedVersionPrefix) {
+			log.WithFields(log.Fields{
+				"version": jsonFeed.Version,
+			}).Error("Unsupported JSON document")
+			return nil, fmt.Errorf("%w: JSON document without a JSON Feed version", ErrUnsupportedFeedFormat)
+		}
+		return jsonFeed.ToRSSFeed(), nil
+	}
+
+	root, err := rootElement(dat, transcoded)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("Failed to detect feed format")
+		return nil, err
+	}
+
+	switch root.Local {
+	case "rss":
+		var rssFeed models.RSSFeed
+		if err := newXMLDecoder(dat, transcoded).Decode(&rssFeed); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("Failed to unmarshal RSS feed")
+			return nil, err
+		}
+		return &rssFeed, nil
+
+	case "feed":
+		var atomFeed models.AtomFeed
+		if err := newXMLDecoder(dat, transcoded).Decode(&atomFeed); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("Failed to unmarshal Atom feed")
+			return nil, err
+		}
+		return atomFeed.ToRSSFeed(), nil
+
+	case "RDF":
+		var rdfFeed models.RDFFeed
+		if err := newXMLDecoder(dat, transcoded).Decode(&rdfFeed); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("Failed to unmarshal RDF feed")
+			return nil, err
+		}
+		return rdfFeed.ToRSSFeed(), nil
+	}
+
+	log.WithFields(log.Fields{
+		"root": root.Local,
+	}).Error("Unsupported feed format")
+	return nil, fmt.Errorf("%w: root element <%s>", ErrUnsupportedFeedFormat, root.Local)
+}
+
+// rootElement returns the name of the first element in an XML document.
+func rootElement(dat []byte, transcoded bool) (xml.Name, error) {
+	decoder := newXMLDecoder(dat, transcoded)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return xml.Name{}, ErrUnsupportedFeedFormat
+		}
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// isJSONFeed reports whether a document should be parsed as a JSON Feed.
+func isJSONFeed(dat []byte, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && (mediaType == "application/feed+json" || mediaType == "application/json") {
+		return true
+	}
+
+	// Sniff the document when the server sends a generic or missing Content-Type
+	trimmed := bytes.TrimSpace(dat)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// newXMLDecoder returns a decoder honoring the encoding in the XML declaration.
+// Documents already transcoded from the HTTP charset are read as UTF-8 regardless of the declaration.
+func newXMLDecoder(dat []byte, transcoded bool) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(dat))
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if transcoded {
+			return input, nil
+		}
+		return charset.NewReaderLabel(label, input)
+	}
+	return decoder
+}
+
+// transcodeToUTF8 converts a document to UTF-8 using the charset of its Content-Type.
+// It reports whether the document was transcoded; documents without a usable charset
+// are returned unchanged.
+func transcodeToUTF8(dat []byte, contentType string) ([]byte, bool) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["charset"] == "" {
+		return dat, false
+	}
+
+	label := strings.ToLower(strings.TrimSpace(params["charset"]))
+	reader, err := charset.NewReaderLabel(label, bytes.NewReader(dat))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"charset": label,
+			"error":   err,
+		}).Warn("Unsupported charset, falling back to the XML declaration")
+		return dat, false
+	}
+
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"charset": label,
+			"error":   err,
+		}).Warn("Failed to decode charset, falling back to the XML declaration")
+		return dat, false
+	}
+	return decoded, true
+}
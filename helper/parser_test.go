@@ -0,0 +1,406 @@
+package helper
+
+import (
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/qmranik/rss-aggregator-backend/models"
+)
+
+// readFixture returns the contents of a file in testdata.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	dat, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("reading fixture %s: %v", name, err)
+	}
+	return dat
+}
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		fixture     string
+		contentType string
+		wantTitle   string
+		wantLink    string
+		wantItems   []models.RSSItem // only Title, Link, GUID and PubDate are compared
+	}{
+		{
+			name:        "rss",
+			fixture:     "rss.xml",
+			contentType: "application/rss+xml",
+			wantTitle:   "Example Blog",
+			wantLink:    "https://example.com/",
+			wantItems: []models.RSSItem{
+				{Title: "First post", Link: "https://example.com/posts/first", GUID: "example-1", PubDate: "Mon, 02 Jan 2006 15:04:05 +0000"},
+				{Title: "Undated post", Link: "https://example.com/posts/undated"},
+			},
+		},
+		{
+			name:        "rss without content type",
+			fixture:     "rss.xml",
+			contentType: "",
+			wantTitle:   "Example Blog",
+			wantLink:    "https://example.com/",
+			wantItems: []models.RSSItem{
+				{Title: "First post", Link: "https://example.com/posts/first", GUID: "example-1", PubDate: "Mon, 02 Jan 2006 15:04:05 +0000"},
+				{Title: "Undated post", Link: "https://example.com/posts/undated"},
+			},
+		},
+		{
+			name:        "atom",
+			fixture:     "atom.xml",
+			contentType: "application/atom+xml; charset=utf-8",
+			wantTitle:   "Example Atom",
+			wantLink:    "https://example.org/",
+			wantItems: []models.RSSItem{
+				{Title: "Published entry", Link: "https://example.org/entries/1", GUID: "tag:example.org,2006:1"},
+				{Title: "Updated entry", Link: "https://example.org/entries/2", GUID: "tag:example.org,2006:2"},
+				{Title: "Using &lt;div&gt; elements", Link: "https://example.org/entries/3", GUID: "tag:example.org,2006:3"},
+			},
+		},
+		{
+			name:        "rdf",
+			fixture:     "rdf.xml",
+			contentType: "application/rdf+xml",
+			wantTitle:   "Example RDF",
+			wantLink:    "https://example.net/",
+			wantItems: []models.RSSItem{
+				{Title: "Dated article", Link: "https://example.net/articles/1?ref=rss", GUID: "https://example.net/articles/1"},
+				{Title: "Article without a link", Link: "https://example.net/articles/2", GUID: "https://example.net/articles/2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := ParseFeed(readFixture(t, tt.fixture), tt.contentType)
+			if err != nil {
+				t.Fatalf("ParseFeed() error = %v", err)
+			}
+			if feed.Channel.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", feed.Channel.Title, tt.wantTitle)
+			}
+			if feed.Channel.Link != tt.wantLink {
+				t.Errorf("Link = %q, want %q", feed.Channel.Link, tt.wantLink)
+			}
+			if len(feed.Channel.Item) != len(tt.wantItems) {
+				t.Fatalf("got %d items, want %d", len(feed.Channel.Item), len(tt.wantItems))
+			}
+			for i, want := range tt.wantItems {
+				got := feed.Channel.Item[i]
+				got = models.RSSItem{Title: got.Title, Link: got.Link, GUID: got.GUID, PubDate: got.PubDate}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("item %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRDFFeed(t *testing.T) {
+	feed, err := ParseFeed(readFixture(t, "rdf.xml"), "application/xml")
+	if err != nil {
+		t.Fatalf("ParseFeed() error = %v", err)
+	}
+	if feed.Channel.Language != "de" {
+		t.Errorf("Language = %q, want %q", feed.Channel.Language, "de")
+	}
+	if feed.Channel.Image.URL != "https://example.net/logo.png" {
+		t.Errorf("Image.URL = %q, want %q", feed.Channel.Image.URL, "https://example.net/logo.png")
+	}
+	if len(feed.Channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Channel.Item))
+	}
+
+	// Items are siblings of the channel and keep their Dublin Core metadata
+	dated := feed.Channel.Item[0]
+	if !reflect.DeepEqual(dated.Categories, []string{"news", "science"}) {
+		t.Errorf("Categories = %q, want [news science]", dated.Categories)
+	}
+	if !reflect.DeepEqual(dated.Creators, []string{"Erika Mustermann"}) {
+		t.Errorf("Creators = %q, want [Erika Mustermann]", dated.Creators)
+	}
+	published, source := ItemPublishedAt(dated, time.Now())
+	if want := time.Date(2006, 1, 2, 13, 4, 5, 0, time.UTC); !published.Equal(want) || source != DateSourceDCDate {
+		t.Errorf("ItemPublishedAt() = %v, %q, want %v, %q", published, source, want, DateSourceDCDate)
+	}
+
+	undated := feed.Channel.Item[1]
+	if _, source := ItemPublishedAt(undated, time.Now()); source != DateSourceFirstSeen {
+		t.Errorf("ItemPublishedAt() source = %q, want %q", source, DateSourceFirstSeen)
+	}
+}
+
+func TestParseFeedErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		dat     string
+		wantErr error
+	}{
+		{name: "html page", dat: "<html><body>Not a feed</body></html>", wantErr: ErrUnsupportedFeedFormat},
+		{name: "empty document", dat: "", wantErr: ErrUnsupportedFeedFormat},
+		{name: "truncated rss", dat: "<rss><channel><title>Broken"},
+		{name: "json without a feed version", dat: `{"name": "Blog", "routes": {}}`, wantErr: ErrUnsupportedFeedFormat},
+		{name: "json with another version", dat: `{"version": "1.1", "items": []}`, wantErr: ErrUnsupportedFeedFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFeed([]byte(tt.dat), "application/xml")
+			if err == nil {
+				t.Fatal("ParseFeed() error = nil, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseFeed() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFeedCharsets(t *testing.T) {
+	// "Café" in ISO-8859-1 and "日本" in Shift_JIS
+	const latin1, shiftJIS = "Caf\xe9", "\x93\xfa\x96\x7b"
+
+	tests := []struct {
+		name        string
+		declaration string
+		contentType string
+		title       string
+		want        string
+	}{
+		{
+			name:        "iso-8859-1 from the xml declaration",
+			declaration: `<?xml version="1.0" encoding="ISO-8859-1"?>`,
+			contentType: "application/rss+xml",
+			title:       latin1,
+			want:        "Café",
+		},
+		{
+			name:        "iso-8859-1 from the content type",
+			contentType: "application/rss+xml; charset=ISO-8859-1",
+			title:       latin1,
+			want:        "Café",
+		},
+		{
+			name:        "content type wins over a wrong declaration",
+			declaration: `<?xml version="1.0" encoding="UTF-8"?>`,
+			contentType: "text/xml; charset=iso-8859-1",
+			title:       latin1,
+			want:        "Café",
+		},
+		{
+			name:        "shift_jis from the xml declaration",
+			declaration: `<?xml version="1.0" encoding="Shift_JIS"?>`,
+			contentType: "application/xml",
+			title:       shiftJIS,
+			want:        "日本",
+		},
+		{
+			name:        "shift_jis from the content type",
+			declaration: `<?xml version="1.0" encoding="Shift_JIS"?>`,
+			contentType: `application/rss+xml; charset="Shift_JIS"`,
+			title:       shiftJIS,
+			want:        "日本",
+		},
+		{
+			name:        "unknown content type charset falls back to the declaration",
+			declaration: `<?xml version="1.0" encoding="ISO-8859-1"?>`,
+			contentType: "application/rss+xml; charset=x-unknown",
+			title:       latin1,
+			want:        "Café",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dat := tt.declaration + `<rss version="2.0"><channel><title>` + tt.title +
+				`</title><item><title>` + tt.title + `</title></item></channel></rss>`
+			feed, err := ParseFeed([]byte(dat), tt.contentType)
+			if err != nil {
+				t.Fatalf("ParseFeed() error = %v", err)
+			}
+			if feed.Channel.Title != tt.want {
+				t.Errorf("channel title = %q, want %q", feed.Channel.Title, tt.want)
+			}
+			if len(feed.Channel.Item) != 1 || feed.Channel.Item[0].Title != tt.want {
+				t.Errorf("items = %+v, want one titled %q", feed.Channel.Item, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedNamespacedElements(t *testing.T) {
+	dat := `<rss version="2.0" xmlns:slash="http://purl.org/rss/1.0/modules/slash/" xmlns:media="http://search.yahoo.com/mrss/"
+  xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel><title>Namespaces</title>
+<item>
+  <title>Post</title>
+  <link>https://example.com/post</link>
+  <description>Full description</description>
+  <author>host@example.com (Host)</author>
+  <comments>https://example.com/post#comments</comments>
+  <slash:comments>42</slash:comments>
+  <category>Go</category>
+  <media:category scheme="urn:example">Arts/Music</media:category>
+  <itunes:title>Episode title</itunes:title>
+  <itunes:author>Podcast host</itunes:author>
+  <media:title>Media title</media:title>
+  <media:description>Media description</media:description>
+  <atom:link rel="self" href="https://example.com/post.xml"/>
+</item>
+</channel></rss>`
+
+	feed, err := ParseFeed([]byte(dat), "application/rss+xml")
+	if err != nil {
+		t.Fatalf("ParseFeed() error = %v", err)
+	}
+	item := feed.Channel.Item[0]
+	if item.Title != "Post" {
+		t.Errorf("Title = %q, want %q", item.Title, "Post")
+	}
+	if item.Link != "https://example.com/post" {
+		t.Errorf("Link = %q, want the item link", item.Link)
+	}
+	if item.Description != "Full description" {
+		t.Errorf("Description = %q, want %q", item.Description, "Full description")
+	}
+	if item.Author != "host@example.com (Host)" {
+		t.Errorf("Author = %q, want the RSS author", item.Author)
+	}
+	if item.Comments != "https://example.com/post#comments" {
+		t.Errorf("Comments = %q, want the comments link", item.Comments)
+	}
+	if !reflect.DeepEqual(item.Categories, []string{"Go"}) {
+		t.Errorf("Categories = %v, want [Go]", item.Categories)
+	}
+}
+
+func TestAtomFeedToRSSFeed(t *testing.T) {
+	var atomFeed models.AtomFeed
+	if err := xml.Unmarshal(readFixture(t, "atom.xml"), &atomFeed); err != nil {
+		t.Fatalf("decoding fixture: %v", err)
+	}
+	channel := atomFeed.ToRSSFeed().Channel
+
+	if channel.Description != "An <em>Atom</em> feed" {
+		t.Errorf("Description = %q", channel.Description)
+	}
+	if channel.ImageURL() != "https://example.org/logo.png" || channel.Icon != "https://example.org/favicon.png" {
+		t.Errorf("ImageURL() = %q, Icon = %q", channel.ImageURL(), channel.Icon)
+	}
+	hubs, self := channel.WebSubLinks()
+	if !reflect.DeepEqual(hubs, []string{"https://hub.example.org/"}) || self != "https://example.org/atom.xml" {
+		t.Errorf("WebSubLinks() = %v, %q", hubs, self)
+	}
+
+	tests := []struct {
+		name            string
+		wantTitle       string // the title as plain text
+		wantDescription string
+		wantPublished   string
+		wantUpdated     string
+		wantCreators    []string
+		wantCategories  []string
+		wantComments    string
+	}{
+		{
+			name:            "summary, dates, category label and replies link",
+			wantTitle:       "Published entry",
+			wantDescription: "Short summary",
+			wantPublished:   "2006-01-02T15:04:05Z",
+			wantUpdated:     "2006-01-03T10:00:00Z",
+			wantCreators:    []string{"Feed Author"},
+			wantCategories:  []string{"Go"},
+			wantComments:    "https://example.org/entries/1#comments",
+		},
+		{
+			name:            "content fallback and entry author",
+			wantTitle:       "Updated entry",
+			wantDescription: "Only content",
+			wantUpdated:     "2006-02-01T00:00:00Z",
+			wantCreators:    []string{"Entry Author"},
+		},
+		{
+			name:            "markup characters in text constructs",
+			wantTitle:       "Using <div> elements",
+			wantDescription: "a &lt;script&gt; b",
+			wantUpdated:     "2006-03-01T00:00:00Z",
+			wantCreators:    []string{"Feed Author"},
+		},
+	}
+	if len(channel.Item) != len(tests) {
+		t.Fatalf("got %d items, want %d", len(channel.Item), len(tests))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := channel.Item[i]
+			if got := PlainText(item.Title); got != tt.wantTitle {
+				t.Errorf("plain text title = %q, want %q", got, tt.wantTitle)
+			}
+			if item.Description != tt.wantDescription {
+				t.Errorf("Description = %q, want %q", item.Description, tt.wantDescription)
+			}
+			if item.Published != tt.wantPublished || item.Updated != tt.wantUpdated {
+				t.Errorf("Published, Updated = %q, %q, want %q, %q", item.Published, item.Updated, tt.wantPublished, tt.wantUpdated)
+			}
+			if !reflect.DeepEqual(item.Creators, tt.wantCreators) {
+				t.Errorf("Creators = %v, want %v", item.Creators, tt.wantCreators)
+			}
+			if !reflect.DeepEqual(item.Categories, tt.wantCategories) {
+				t.Errorf("Categories = %v, want %v", item.Categories, tt.wantCategories)
+			}
+			if item.Comments != tt.wantComments {
+				t.Errorf("Comments = %q, want %q", item.Comments, tt.wantComments)
+			}
+		})
+	}
+}
+
+func TestParseJSONFeed(t *testing.T) {
+	feed, err := ParseFeed(readFixture(t, "feed.json"), "application/feed+json")
+	if err != nil {
+		t.Fatalf("ParseFeed() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		wantGUID  string
+		wantLink  string
+		wantText  string // the description as plain text after sanitizing
+		wantDated bool
+	}{
+		{name: "content_html", wantGUID: "1", wantLink: "https://example.net/1", wantText: "Hello", wantDated: true},
+		{name: "content_text with markup characters", wantGUID: "2", wantLink: "https://elsewhere.example/2", wantText: "if a < b && c > d then <done>"},
+		{name: "summary only", wantGUID: "3", wantLink: "https://example.net/3", wantText: "Fish & chips"},
+		{name: "numeric id", wantGUID: "4", wantLink: "https://example.net/4", wantText: "Numeric id"},
+	}
+	if len(feed.Channel.Item) != len(tests) {
+		t.Fatalf("got %d items, want %d", len(feed.Channel.Item), len(tests))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := feed.Channel.Item[i]
+			if item.GUID != tt.wantGUID {
+				t.Errorf("GUID = %q, want %q", item.GUID, tt.wantGUID)
+			}
+			if item.Link != tt.wantLink {
+				t.Errorf("Link = %q, want %q", item.Link, tt.wantLink)
+			}
+			if got := PlainText(SanitizeHTML(item.Description, item.Link)); got != tt.wantText {
+				t.Errorf("sanitized description = %q, want %q", got, tt.wantText)
+			}
+			if (item.Published != "") != tt.wantDated {
+				t.Errorf("Published = %q", item.Published)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent identifies the aggregator to the sites it fetches.
+const DefaultUserAgent = "rss-aggregator-backend/1.0 (+https://github.com/qmranik/rss-aggregator-backend)"
+
+const (
+	// DefaultRequestTimeout bounds a request once its host is free, waiting for the host does not count.
+	DefaultRequestTimeout = 10 * time.Second
+	// DefaultMaxHostWait is the longest a request waits for its host before giving up with ErrHostBusy.
+	DefaultMaxHostWait = 30 * time.Second
+)
+
+// ErrHostBusy is returned when a request would wait for its host longer than allowed.
+// The request was not sent, so it says nothing about the resource it targets.
+var ErrHostBusy = errors.New("host busy, request not sent")
+
+// HostLimiter bounds the number of concurrent requests to each host and
+// spaces consecutive requests to the same host by a minimum delay.
+type HostLimiter struct {
+	MaxPerHost int           // MaxPerHost is the maximum number of concurrent requests to a host.
+	MinDelay   time.Duration // MinDelay is the minimum time between the starts of two requests to a host.
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState tracks the in-flight requests and the next free start time of a host.
+type hostState struct {
+	slots      chan struct{}
+	mu         sync.Mutex
+	next       time.Time
+	crawlDelay time.Duration
+}
+
+// NewHostLimiter creates a HostLimiter allowing maxPerHost concurrent requests per host,
+// started at least minDelay apart.
+func NewHostLimiter(maxPerHost int, minDelay time.Duration) *HostLimiter {
+	if maxPerHost < 1 {
+		maxPerHost = 1
+	}
+	return &HostLimiter{
+		MaxPerHost: maxPerHost,
+		MinDelay:   minDelay,
+		hosts:      make(map[string]*hostState),
+	}
+}
+
+// Acquire waits until a request to host may start and returns a function releasing its slot.
+// When the request could only start after the deadline of ctx, it gives up without waiting.
+func (l *HostLimiter) Acquire(ctx context.Context, host string) (func(), error) {
+	state := l.host(host)
+
+	select {
+	case state.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-state.slots }
+
+	// Reserve the next start time for this host, then wait for it
+	state.mu.Lock()
+	start := time.Now()
+	if state.next.After(start) {
+		start = state.next
+	}
+	delay := l.MinDelay
+	if state.crawlDelay > delay {
+		delay = state.crawlDelay
+	}
+	if deadline, ok := ctx.Deadline(); ok && start.After(deadline) {
+		state.mu.Unlock()
+		release()
+		return nil, context.DeadlineExceeded
+	}
+	state.next = start.Add(delay)
+	state.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
+
+// SetCrawlDelay raises the minimum delay between requests to host, as requested by its robots.txt.
+func (l *HostLimiter) SetCrawlDelay(host string, delay time.Duration) {
+	state := l.host(host)
+
+	state.mu.Lock()
+	state.crawlDelay = delay
+	state.mu.Unlock()
+}
+
+// host returns the state of a host, creating it on first use.
+func (l *HostLimiter) host(host string) *hostState {
+	host = strings.ToLower(host)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.hosts[host]
+	if !ok {
+		state = &hostState{slots: make(chan struct{}, l.MaxPerHost)}
+		l.hosts[host] = state
+	}
+	return state
+}
+
+// politeTransport applies the per-host limits, the request timeout and the User-Agent to every request.
+type politeTransport struct {
+	base      http.RoundTripper
+	limiter   *HostLimiter
+	userAgent string
+	timeout   time.Duration // timeout, if set, bounds each request from the moment its host is free.
+	maxWait   time.Duration // maxWait, if set, bounds the wait for the host.
+}
+
+// RoundTrip waits for the host to be free and holds its slot until the response body is closed.
+// The request timeout starts once the slot is acquired, so time spent queueing behind other
+// requests to the same host does not count against it.
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.userAgent != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+
+	release := func() {}
+	if t.limiter != nil {
+		waitCtx := req.Context()
+		if t.maxWait > 0 {
+			var cancel context.CancelFunc
+			waitCtx, cancel = context.WithTimeout(waitCtx, t.maxWait)
+			defer cancel()
+		}
+		var err error
+		release, err = t.limiter.Acquire(waitCtx, req.URL.Hostname())
+		if err != nil {
+			// Giving up on a busy host is not a failure of the request
+			if req.Context().Err() == nil {
+				return nil, ErrHostBusy
+			}
+			return nil, err
+		}
+	}
+
+	if t.timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+		req = req.WithContext(ctx)
+		releaseSlot := release
+		release = func() {
+			cancel()
+			releaseSlot()
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody releases a host slot and the request timeout once the response body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
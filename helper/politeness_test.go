@@ -0,0 +1,85 @@
+package helper
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// get performs a request through client and drains the response.
+func get(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+func TestPoliteTransportTimeoutStartsAfterHostWait(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	// The second request waits longer for the host than its timeout allows for the request itself
+	client := &http.Client{Transport: &politeTransport{
+		base:    http.DefaultTransport,
+		limiter: NewHostLimiter(1, 300*time.Millisecond),
+		timeout: 200 * time.Millisecond,
+		maxWait: time.Second,
+	}}
+	for i := 0; i < 2; i++ {
+		if err := get(client, srv.URL); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+}
+
+func TestPoliteTransportRequestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &politeTransport{
+		base:    http.DefaultTransport,
+		limiter: NewHostLimiter(1, 0),
+		timeout: 50 * time.Millisecond,
+	}}
+	if err := get(client, srv.URL); err == nil {
+		t.Fatal("slow request succeeded, want a timeout")
+	}
+}
+
+func TestPoliteTransportHostBusy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &politeTransport{
+		base:    http.DefaultTransport,
+		limiter: NewHostLimiter(1, time.Minute),
+		timeout: time.Second,
+		maxWait: 100 * time.Millisecond,
+	}}
+	if err := get(client, srv.URL); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+
+	// The host is reserved for a minute, give up at once instead of waiting out maxWait
+	started := time.Now()
+	err := get(client, srv.URL)
+	if !errors.Is(err, ErrHostBusy) {
+		t.Fatalf("second request error = %v, want %v", err, ErrHostBusy)
+	}
+	if elapsed := time.Since(started); elapsed > 50*time.Millisecond {
+		t.Errorf("gave up after %v, want immediately", elapsed)
+	}
+}
@@ -0,0 +1,212 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// ErrNoArticleContent is returned when no readable content can be extracted from a page.
+var ErrNoArticleContent = errors.New("no readable article content")
+
+// maxArticleBodySize bounds how much of an article page is read.
+const maxArticleBodySize = 5 << 20
+
+var (
+	// positiveHints mark class or id values of elements likely to hold the article.
+	positiveHints = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text`)
+	// negativeHints mark class or id values of page chrome around the article.
+	negativeHints = regexp.MustCompile(`(?i)ad-|ads|banner|comment|footer|header|menu|meta|nav|promo|related|share|sidebar|social|sponsor|widget`)
+)
+
+// unreadableElements never contain article content.
+var unreadableElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Iframe:   true,
+	atom.Button:   true,
+	atom.Select:   true,
+	atom.Svg:      true,
+}
+
+// ArticleFetcher retrieves the readable content of an article page.
+type ArticleFetcher interface {
+	FetchArticle(ctx context.Context, articleURL string) (string, error)
+}
+
+// FetchArticle downloads an article page and extracts its main readable content as HTML.
+func (f *HTTPFetcher) FetchArticle(ctx context.Context, articleURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, articleURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"articleURL": articleURL,
+			"error":      err,
+		}).Error("Failed to fetch article")
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &StatusError{StatusCode: resp.StatusCode}
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return "", ErrNoArticleContent
+	}
+
+	// Decode the page to UTF-8 from its Content-Type or <meta charset>
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxArticleBodySize), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", err
+	}
+
+	doc, err := html.Parse(body)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"articleURL": articleURL,
+			"error":      err,
+		}).Error("Failed to parse article")
+		return "", err
+	}
+
+	return ExtractArticle(doc)
+}
+
+// ExtractArticle finds the main content of an HTML document with readability-style
+// heuristics: paragraphs score their parent and grandparent by the amount of text they
+// hold, class and id names adjust the score, and link-heavy elements are penalised.
+// The inner HTML of the best scoring element is returned.
+func ExtractArticle(doc *html.Node) (string, error) {
+	removeUnreadable(doc)
+
+	scores := make(map[*html.Node]float64)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.P || n.DataAtom == atom.Pre || n.DataAtom == atom.Blockquote) {
+			text := strings.TrimSpace(textContent(n))
+			if len(text) >= 25 {
+				// Longer paragraphs with more clauses are more likely article text
+				score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text)/100), 3)
+				if parent := n.Parent; parent != nil {
+					scores[parent] += score
+					if grandparent := parent.Parent; grandparent != nil {
+						scores[grandparent] += score / 2
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var best *html.Node
+	var bestScore float64
+	for n, score := range scores {
+		score = (score + classWeight(n)) * (1 - linkDensity(n))
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	if best == nil {
+		return "", ErrNoArticleContent
+	}
+
+	var buf bytes.Buffer
+	for c := best.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&buf, c); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// removeUnreadable detaches elements that never hold article content.
+func removeUnreadable(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && unreadableElements[c.DataAtom]) {
+			n.RemoveChild(c)
+		} else {
+			removeUnreadable(c)
+		}
+		c = next
+	}
+}
+
+// classWeight scores an element by its class and id names.
+func classWeight(n *html.Node) float64 {
+	var weight float64
+	for _, a := range n.Attr {
+		if a.Key != "class" && a.Key != "id" {
+			continue
+		}
+		if negativeHints.MatchString(a.Val) {
+			weight -= 25
+		}
+		if positiveHints.MatchString(a.Val) {
+			weight += 25
+		}
+	}
+	if n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		weight += 25
+	}
+	return weight
+}
+
+// linkDensity returns the share of an element's text that sits inside links.
+func linkDensity(n *html.Node) float64 {
+	textLength := len(textContent(n))
+	if textLength == 0 {
+		return 0
+	}
+
+	linkLength := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			linkLength += len(textContent(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return float64(linkLength) / float64(textLength)
+}
+
+// textContent returns the concatenated text of a node and its descendants.
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
@@ -0,0 +1,323 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrBlockedByRobots is returned when robots.txt disallows fetching a URL.
+var ErrBlockedByRobots = errors.New("blocked by robots.txt")
+
+const (
+	// robotsCacheTTL is how long a host's robots.txt is cached.
+	robotsCacheTTL = 24 * time.Hour
+	// robotsUnavailableTTL is how long a host whose robots.txt is unreachable stays disallowed.
+	robotsUnavailableTTL = time.Hour
+	// maxRobotsSize bounds how much of a robots.txt file is read.
+	maxRobotsSize = 512 << 10
+	// robotsFetchTimeout bounds a robots.txt fetch, including the wait for its host.
+	robotsFetchTimeout = time.Minute
+)
+
+// RobotsChecker decides whether URLs may be fetched according to their host's robots.txt.
+// Rules are cached per scheme and host, and concurrent lookups of a host share a single fetch.
+type RobotsChecker struct {
+	Client    *http.Client // Client fetches robots.txt files.
+	UserAgent string       // UserAgent selects the robots.txt group that applies to us.
+	Limiter   *HostLimiter // Limiter, if set, is told about each host's Crawl-delay.
+
+	mu       sync.Mutex
+	cache    map[string]robotsEntry
+	inflight map[string]*robotsFetch
+}
+
+// robotsFetch is a robots.txt download shared by every lookup waiting for it.
+type robotsFetch struct {
+	done  chan struct{}
+	entry robotsEntry
+	err   error
+}
+
+// robotsEntry is a cached robots.txt group with its expiry.
+type robotsEntry struct {
+	rules   robotsRules
+	expires time.Time
+}
+
+// robotsRules are the rules of the robots.txt group that applies to our user agent.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	disallowed bool // disallowed blocks everything, used when robots.txt is unreachable.
+}
+
+// robotsRule is a single Allow or Disallow line.
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// NewRobotsChecker creates a RobotsChecker fetching robots.txt with client.
+func NewRobotsChecker(client *http.Client, userAgent string, limiter *HostLimiter) *RobotsChecker {
+	return &RobotsChecker{
+		Client:    client,
+		UserAgent: userAgent,
+		Limiter:   limiter,
+		cache:     make(map[string]robotsEntry),
+		inflight:  make(map[string]*robotsFetch),
+	}
+}
+
+// Allowed reports whether robots.txt permits fetching u.
+func (c *RobotsChecker) Allowed(ctx context.Context, u *url.URL) (bool, error) {
+	entry, err := c.rules(ctx, u)
+	if err != nil {
+		return false, err
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return entry.rules.allows(path), nil
+}
+
+// rules returns the cached robots.txt of u's host, fetching it when missing or expired.
+// The fetch runs with its own timeout rather than the deadline of the request that
+// triggered it, as every concurrent lookup of the host waits for the same fetch.
+func (c *RobotsChecker) rules(ctx context.Context, u *url.URL) (robotsEntry, error) {
+	key := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	if entry, ok := c.cache[key]; ok && time.Now().Before(entry.expires) {
+		c.mu.Unlock()
+		return entry, nil
+	}
+	pending, ok := c.inflight[key]
+	if !ok {
+		pending = &robotsFetch{done: make(chan struct{})}
+		c.inflight[key] = pending
+		go c.fetchShared(key, u.Hostname(), pending)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-pending.done:
+		return pending.entry, pending.err
+	case <-ctx.Done():
+		return robotsEntry{}, ctx.Err()
+	}
+}
+
+// fetchShared fetches the robots.txt of a host for every lookup waiting on pending,
+// caching it and passing its Crawl-delay to the limiter before waking them up.
+func (c *RobotsChecker) fetchShared(key, host string, pending *robotsFetch) {
+	defer close(pending.done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), robotsFetchTimeout)
+	defer cancel()
+	pending.entry, pending.err = c.fetch(ctx, key)
+
+	c.mu.Lock()
+	if pending.err == nil {
+		c.cache[key] = pending.entry
+	}
+	delete(c.inflight, key)
+	c.mu.Unlock()
+
+	if pending.err == nil && c.Limiter != nil {
+		c.Limiter.SetCrawlDelay(host, pending.entry.rules.crawlDelay)
+	}
+}
+
+// fetch downloads and parses the robots.txt of a scheme and host.
+func (c *RobotsChecker) fetch(ctx context.Context, key string) (robotsEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, key+"/robots.txt", nil)
+	if err != nil {
+		return robotsEntry{}, err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"host":  key,
+			"error": err,
+		}).Error("Failed to fetch robots.txt")
+		return robotsEntry{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		dat, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+		if err != nil {
+			return robotsEntry{}, err
+		}
+		return robotsEntry{
+			rules:   parseRobots(dat, c.UserAgent),
+			expires: time.Now().Add(robotsCacheTTL),
+		}, nil
+
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// No robots.txt (or not accessible to anyone) means no restrictions
+		return robotsEntry{expires: time.Now().Add(robotsCacheTTL)}, nil
+
+	default:
+		// A server error means the rules are unknown, assume everything is disallowed
+		log.WithFields(log.Fields{
+			"host":   key,
+			"status": resp.StatusCode,
+		}).Warn("robots.txt unavailable, treating host as disallowed")
+		return robotsEntry{
+			rules:   robotsRules{disallowed: true},
+			expires: time.Now().Add(robotsUnavailableTTL),
+		}, nil
+	}
+}
+
+// parseRobots extracts the rules that apply to userAgent from a robots.txt file.
+// Groups naming our product token take precedence over the "*" group.
+func parseRobots(dat []byte, userAgent string) robotsRules {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	var specific, wildcard robotsRules
+	var agents []string
+	hasSpecific := false
+	inAgentLines := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(dat))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			// Consecutive user-agent lines share the group that follows them
+			if !inAgentLines {
+				agents = nil
+			}
+			agents = append(agents, strings.ToLower(value))
+			inAgentLines = true
+			continue
+		}
+		inAgentLines = false
+
+		for _, agent := range agents {
+			var group *robotsRules
+			switch {
+			case agent == "*":
+				group = &wildcard
+			case agent != "" && strings.HasPrefix(token, agent):
+				group = &specific
+				hasSpecific = true
+			default:
+				continue
+			}
+
+			switch key {
+			case "allow", "disallow":
+				// An empty Disallow allows everything and adds no rule
+				if value != "" {
+					group.rules = append(group.rules, robotsRule{allow: key == "allow", pattern: value})
+				}
+			case "crawl-delay":
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					group.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	if hasSpecific {
+		return specific
+	}
+	return wildcard
+}
+
+// allows applies the longest matching rule to path, preferring Allow on ties.
+func (r robotsRules) allows(path string) bool {
+	if r.disallowed {
+		return false
+	}
+
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			allowed, longest = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// matchRobotsPattern matches a path against a robots.txt pattern, where "*" matches
+// any sequence of characters and a trailing "$" anchors the end of the path.
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+
+	if !anchored {
+		return true
+	}
+	// The last literal part must end the path; retry when it occurs again later
+	last := parts[len(parts)-1]
+	return rest == "" || (len(parts) > 1 && strings.HasSuffix(path, last))
+}
+
+// robotsTransport refuses requests that robots.txt disallows.
+type robotsTransport struct {
+	next    http.RoundTripper
+	checker *RobotsChecker
+}
+
+// RoundTrip checks the request URL against robots.txt before passing it on.
+func (t *robotsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path != "/robots.txt" {
+		allowed, err := t.checker.Allowed(req.Context(), req.URL)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, ErrBlockedByRobots
+		}
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,243 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	const userAgent = "rss-aggregator-backend/1.0 (+https://example.com)"
+
+	tests := []struct {
+		name           string
+		robots         string
+		wantAllowed    []string
+		wantDisallowed []string
+		wantDelay      time.Duration
+	}{
+		{
+			name:           "wildcard group",
+			robots:         "User-agent: *\nDisallow: /private/\nAllow: /private/feed.xml\n",
+			wantAllowed:    []string{"/", "/feed.xml", "/private/feed.xml"},
+			wantDisallowed: []string{"/private/", "/private/other.xml"},
+		},
+		{
+			name:           "our group takes precedence over the wildcard group",
+			robots:         "User-agent: *\nDisallow: /\n\nUser-agent: rss-aggregator-backend\nDisallow: /drafts\nCrawl-delay: 2.5\n",
+			wantAllowed:    []string{"/", "/feed.xml"},
+			wantDisallowed: []string{"/drafts", "/drafts/1"},
+			wantDelay:      2500 * time.Millisecond,
+		},
+		{
+			name:           "consecutive user-agent lines share a group",
+			robots:         "User-agent: otherbot\nUser-agent: RSS-Aggregator-Backend\nDisallow: /feeds/\n",
+			wantAllowed:    []string{"/"},
+			wantDisallowed: []string{"/feeds/all.xml"},
+		},
+		{
+			name:        "other agents only",
+			robots:      "User-agent: otherbot\nDisallow: /\n",
+			wantAllowed: []string{"/", "/feed.xml"},
+		},
+		{
+			name:        "empty disallow and comments",
+			robots:      "# comment\nUser-agent: * # everyone\nDisallow:\nCrawl-delay: nonsense\n",
+			wantAllowed: []string{"/", "/anything"},
+		},
+		{
+			name:           "allow wins ties",
+			robots:         "User-agent: *\nDisallow: /page\nAllow: /page\nDisallow: /*.php$\n",
+			wantAllowed:    []string{"/page", "/index.php?x=1"},
+			wantDisallowed: []string{"/index.php"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := parseRobots([]byte(tt.robots), userAgent)
+			for _, path := range tt.wantAllowed {
+				if !rules.allows(path) {
+					t.Errorf("allows(%q) = false, want true", path)
+				}
+			}
+			for _, path := range tt.wantDisallowed {
+				if rules.allows(path) {
+					t.Errorf("allows(%q) = true, want false", path)
+				}
+			}
+			if rules.crawlDelay != tt.wantDelay {
+				t.Errorf("crawlDelay = %v, want %v", rules.crawlDelay, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestMatchRobotsPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/", "/anything", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish", false},
+		{"/fish/", "/fish", false},
+		{"/*.xml", "/feeds/all.xml", true},
+		{"/*.xml", "/feeds/all.json", false},
+		{"/*.xml$", "/feed.xml", true},
+		{"/*.xml$", "/feed.xml?page=2", false},
+		{"/*.xml$", "/a.xml/b.xml", true},
+		{"/feed$", "/feed", true},
+		{"/feed$", "/feeds", false},
+		{"/a*b*c", "/a-b-c-d", true},
+		{"/a*b*c", "/a-c-b", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchRobotsPattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchRobotsPattern(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+// robotsServer serves robots.txt with status and body, counting the requests for it,
+// and answers every other path with an empty RSS feed.
+func robotsServer(t *testing.T, status int, body string, delay time.Duration) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte(`<rss version="2.0"><channel><title>Feed</title></channel></rss>`))
+			return
+		}
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(delay)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestRobotsCheckerAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		path    string
+		allowed bool
+	}{
+		{name: "allowed path", status: http.StatusOK, body: "User-agent: *\nDisallow: /private\n", path: "/feed.xml", allowed: true},
+		{name: "disallowed path", status: http.StatusOK, body: "User-agent: *\nDisallow: /private\n", path: "/private/feed.xml"},
+		{name: "disallowed query", status: http.StatusOK, body: "User-agent: *\nDisallow: /*?format=rss\n", path: "/blog?format=rss"},
+		{name: "missing robots.txt", status: http.StatusNotFound, path: "/private/feed.xml", allowed: true},
+		{name: "robots.txt unavailable", status: http.StatusServiceUnavailable, path: "/feed.xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := robotsServer(t, tt.status, tt.body, 0)
+			checker := NewRobotsChecker(srv.Client(), DefaultUserAgent, nil)
+
+			allowed, err := checker.Allowed(context.Background(), mustParseURL(t, srv.URL+tt.path))
+			if err != nil {
+				t.Fatalf("Allowed() error = %v", err)
+			}
+			if allowed != tt.allowed {
+				t.Errorf("Allowed() = %v, want %v", allowed, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestRobotsCheckerSharesFetches(t *testing.T) {
+	srv, hits := robotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private\n", 50*time.Millisecond)
+	checker := NewRobotsChecker(srv.Client(), DefaultUserAgent, nil)
+	u := mustParseURL(t, srv.URL+"/feed.xml")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if allowed, err := checker.Allowed(context.Background(), u); err != nil || !allowed {
+				t.Errorf("Allowed() = %v, %v, want true", allowed, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(hits); got != 1 {
+		t.Errorf("robots.txt fetched %d times, want 1", got)
+	}
+}
+
+func TestRobotsCheckerOutlivesRequestDeadline(t *testing.T) {
+	srv, hits := robotsServer(t, http.StatusOK, "User-agent: *\nDisallow:\n", 100*time.Millisecond)
+	checker := NewRobotsChecker(srv.Client(), DefaultUserAgent, nil)
+	u := mustParseURL(t, srv.URL+"/feed.xml")
+
+	// The lookup gives up, but the fetch it started completes and is cached
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := checker.Allowed(ctx, u); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Allowed() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if allowed, err := checker.Allowed(context.Background(), u); err != nil || !allowed {
+		t.Fatalf("Allowed() = %v, %v, want true", allowed, err)
+	}
+	if got := atomic.LoadInt32(hits); got != 1 {
+		t.Errorf("robots.txt fetched %d times, want 1", got)
+	}
+}
+
+func TestRobotsCheckerCrawlDelay(t *testing.T) {
+	srv, _ := robotsServer(t, http.StatusOK, "User-agent: *\nCrawl-delay: 3\n", 0)
+	limiter := NewHostLimiter(1, time.Second)
+	checker := NewRobotsChecker(srv.Client(), DefaultUserAgent, limiter)
+	u := mustParseURL(t, srv.URL+"/feed.xml")
+
+	if _, err := checker.Allowed(context.Background(), u); err != nil {
+		t.Fatalf("Allowed() error = %v", err)
+	}
+	state := limiter.host(u.Hostname())
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.crawlDelay != 3*time.Second {
+		t.Errorf("crawl delay = %v, want 3s", state.crawlDelay)
+	}
+}
+
+func TestHTTPFetcherHonorsRobots(t *testing.T) {
+	srv, hits := robotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private\n", 0)
+	fetcher := NewHTTPFetcherWithTransport(http.DefaultTransport, "", NewHostLimiter(2, 0))
+	ctx := context.Background()
+
+	if _, err := fetcher.FetchFeed(ctx, srv.URL+"/feed.xml", "", "", nil); err != nil {
+		t.Errorf("FetchFeed() allowed feed error = %v", err)
+	}
+	if _, err := fetcher.FetchFeed(ctx, srv.URL+"/private/feed.xml", "", "", nil); !errors.Is(err, ErrBlockedByRobots) {
+		t.Errorf("FetchFeed() disallowed feed error = %v, want %v", err, ErrBlockedByRobots)
+	}
+	if got := atomic.LoadInt32(hits); got != 1 {
+		t.Errorf("robots.txt fetched %d times, want 1", got)
+	}
+}
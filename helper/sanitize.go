@@ -0,0 +1,353 @@
+package helper
+
+import (
+	"bytes"
+	"database/sql"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/qmranik/rss-aggregator-backend/internal/database"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxPreviewLength is the number of characters kept in a plain-text preview.
+const maxPreviewLength = 300
+
+// allowedElements maps the elements kept by SanitizeHTML to their allowed attributes.
+var allowedElements = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Abbr:       {"title"},
+	atom.B:          nil,
+	atom.Blockquote: {"cite"},
+	atom.Br:         nil,
+	atom.Caption:    nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        nil,
+	atom.Div:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title", "width", "height"},
+	atom.Ins:        nil,
+	atom.Kbd:        nil,
+	atom.Li:         nil,
+	atom.Ol:         nil,
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.Q:          {"cite"},
+	atom.S:          nil,
+	atom.Small:      nil,
+	atom.Span:       nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan"},
+	atom.Thead:      nil,
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// droppedElements are removed together with their content.
+var droppedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Applet:   true,
+	atom.Form:     true,
+	atom.Input:    true,
+	atom.Button:   true,
+	atom.Select:   true,
+	atom.Textarea: true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Head:     true,
+	atom.Title:    true,
+	atom.Meta:     true,
+	atom.Link:     true,
+	atom.Base:     true,
+	atom.Svg:      true,
+	atom.Math:     true,
+}
+
+// allowedEmbeds lists the iframe hosts and path prefixes kept as embeds.
+var allowedEmbeds = map[string]string{
+	"www.youtube.com":          "/embed/",
+	"www.youtube-nocookie.com": "/embed/",
+	"player.vimeo.com":         "/video/",
+}
+
+// urlAttributes hold URLs that are resolved and checked against allowedSchemes.
+var urlAttributes = map[string]bool{
+	"href": true,
+	"src":  true,
+	"cite": true,
+}
+
+// allowedSchemes are the URL schemes kept in sanitized HTML.
+var allowedSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// blockElements start a new line in plain-text renditions.
+var blockElements = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Div:        true,
+	atom.Br:         true,
+	atom.Li:         true,
+	atom.Blockquote: true,
+	atom.Pre:        true,
+	atom.Tr:         true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+}
+
+// SanitizeHTML filters untrusted HTML through an allowlist policy. Scripts, styles,
+// forms and iframes (except allowlisted video embeds) are removed with their content,
+// other unknown elements are unwrapped, event handlers and style attributes are dropped,
+// and relative URLs are resolved against baseURL with only http, https and mailto kept.
+func SanitizeHTML(input string, baseURL string) string {
+	base, _ := url.Parse(baseURL)
+
+	nodes, err := html.ParseFragment(strings.NewReader(input), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return html.EscapeString(input)
+	}
+
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		for _, clean := range sanitizeNode(n, base) {
+			if err := html.Render(&buf, clean); err != nil {
+				return html.EscapeString(input)
+			}
+		}
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// sanitizeNode returns the allowed replacement nodes for n.
+func sanitizeNode(n *html.Node, base *url.URL) []*html.Node {
+	switch n.Type {
+	case html.TextNode:
+		return []*html.Node{{Type: html.TextNode, Data: n.Data}}
+
+	case html.ElementNode:
+		if n.DataAtom == atom.Iframe {
+			if embed := sanitizeEmbed(n, base); embed != nil {
+				return []*html.Node{embed}
+			}
+			return nil
+		}
+		if droppedElements[n.DataAtom] {
+			return nil
+		}
+
+		children := sanitizeChildren(n, base)
+		attrs, allowed := allowedElements[n.DataAtom]
+		if !allowed {
+			// Unknown elements are unwrapped to keep their text
+			return children
+		}
+
+		clean := &html.Node{
+			Type:     html.ElementNode,
+			Data:     n.Data,
+			DataAtom: n.DataAtom,
+			Attr:     sanitizeAttrs(n.Attr, attrs, base),
+		}
+		if n.DataAtom == atom.A {
+			clean.Attr = append(clean.Attr, html.Attribute{Key: "rel", Val: "nofollow noopener noreferrer"})
+		}
+		for _, child := range children {
+			clean.AppendChild(child)
+		}
+		return []*html.Node{clean}
+
+	default:
+		// Comments, doctypes and processing instructions are dropped
+		return nil
+	}
+}
+
+// sanitizeChildren sanitizes the children of n.
+func sanitizeChildren(n *html.Node, base *url.URL) []*html.Node {
+	var children []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		children = append(children, sanitizeNode(c, base)...)
+	}
+	return children
+}
+
+// sanitizeAttrs keeps the allowed attributes, resolving and checking URL values.
+func sanitizeAttrs(attrs []html.Attribute, allowed []string, base *url.URL) []html.Attribute {
+	var clean []html.Attribute
+	for _, a := range attrs {
+		if a.Namespace != "" || !contains(allowed, a.Key) {
+			continue
+		}
+		if urlAttributes[a.Key] {
+			resolved, ok := safeURL(a.Val, base)
+			if !ok {
+				continue
+			}
+			a.Val = resolved
+		}
+		clean = append(clean, html.Attribute{Key: a.Key, Val: a.Val})
+	}
+	return clean
+}
+
+// sanitizeEmbed keeps an iframe pointing at an allowlisted video player.
+func sanitizeEmbed(n *html.Node, base *url.URL) *html.Node {
+	for _, a := range n.Attr {
+		if a.Key != "src" {
+			continue
+		}
+		resolved, ok := safeURL(a.Val, base)
+		if !ok {
+			return nil
+		}
+		u, err := url.Parse(resolved)
+		if err != nil || u.Scheme != "https" {
+			return nil
+		}
+		prefix, allowed := allowedEmbeds[strings.ToLower(u.Hostname())]
+		if !allowed || !strings.HasPrefix(u.Path, prefix) {
+			return nil
+		}
+		return &html.Node{
+			Type:     html.ElementNode,
+			Data:     "iframe",
+			DataAtom: atom.Iframe,
+			Attr: append(
+				sanitizeAttrs(n.Attr, []string{"width", "height"}, base),
+				html.Attribute{Key: "src", Val: resolved},
+				html.Attribute{Key: "allowfullscreen", Val: ""},
+				html.Attribute{Key: "sandbox", Val: "allow-scripts allow-same-origin allow-presentation"},
+			),
+		}
+	}
+	return nil
+}
+
+// safeURL resolves a URL against base and reports whether its scheme is allowed.
+func safeURL(raw string, base *url.URL) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if !allowedSchemes[strings.ToLower(u.Scheme)] {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// contains reports whether values contains value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// PlainText renders HTML as plain text with collapsed whitespace.
+func PlainText(input string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(input), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return strings.Join(strings.Fields(input), " ")
+	}
+
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			sb.WriteString(n.Data)
+			return
+		case n.Type == html.ElementNode && droppedElements[n.DataAtom]:
+			return
+		case n.Type == html.ElementNode && blockElements[n.DataAtom]:
+			sb.WriteString(" ")
+			defer sb.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// Preview returns a short plain-text rendition of HTML for post previews.
+func Preview(input string) string {
+	text := PlainText(input)
+	if utf8.RuneCountInString(text) <= maxPreviewLength {
+		return text
+	}
+
+	// Cut on a word boundary where possible
+	runes := []rune(text)[:maxPreviewLength]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > maxPreviewLength/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// SanitizeStoredPost sanitizes the description and full content of a post stored before
+// HTML was sanitized on ingest, and recomputes its preview. Sanitized posts are returned as is.
+func SanitizeStoredPost(post database.Post) database.Post {
+	if post.Sanitized {
+		return post
+	}
+	if post.Description.Valid {
+		post.Description.String = SanitizeHTML(post.Description.String, post.Url)
+		post.Preview = sql.NullString{String: Preview(post.Description.String), Valid: true}
+	}
+	if post.Content.Valid {
+		post.Content.String = SanitizeHTML(post.Content.String, post.Url)
+	}
+	post.Sanitized = true
+	return post
+}
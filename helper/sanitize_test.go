@@ -0,0 +1,117 @@
+package helper
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	const base = "https://example.com/posts/1"
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "script removed with content",
+			input: `<p>Hello</p><script>alert(1)</script>`,
+			want:  `<p>Hello</p>`,
+		},
+		{
+			name:  "style removed with content",
+			input: `<style>p { color: red }</style><p>Hello</p>`,
+			want:  `<p>Hello</p>`,
+		},
+		{
+			name:  "event handlers and style attributes dropped",
+			input: `<p onclick="alert(1)" style="color: red">Hi <img src="/a.png" onerror="alert(1)" alt="a"></p>`,
+			want:  `<p>Hi <img src="https://example.com/a.png" alt="a"/></p>`,
+		},
+		{
+			name:  "unknown elements unwrapped",
+			input: `<article><section>Text</section></article>`,
+			want:  `Text`,
+		},
+		{
+			name:  "javascript href dropped",
+			input: `<a href="javascript:alert(1)">x</a>`,
+			want:  `<a rel="nofollow noopener noreferrer">x</a>`,
+		},
+		{
+			name:  "mixed case scheme dropped",
+			input: `<a href="JaVaScRiPt:alert(1)">x</a>`,
+			want:  `<a rel="nofollow noopener noreferrer">x</a>`,
+		},
+		{
+			name:  "leading whitespace before scheme dropped",
+			input: `<a href=" &#10; javascript:alert(1)">x</a>`,
+			want:  `<a rel="nofollow noopener noreferrer">x</a>`,
+		},
+		{
+			name:  "tab inside scheme dropped",
+			input: `<a href="java&#9;script:alert(1)">x</a>`,
+			want:  `<a rel="nofollow noopener noreferrer">x</a>`,
+		},
+		{
+			name:  "data image src dropped",
+			input: `<img src="data:image/svg+xml;base64,PHN2Zz4=" alt="x">`,
+			want:  `<img alt="x"/>`,
+		},
+		{
+			name:  "mailto kept",
+			input: `<a href="mailto:me@example.com">mail</a>`,
+			want:  `<a href="mailto:me@example.com" rel="nofollow noopener noreferrer">mail</a>`,
+		},
+		{
+			name:  "relative urls resolved",
+			input: `<a href="../about">about</a><img src="img/a.png"><blockquote cite="/source">q</blockquote>`,
+			want:  `<a href="https://example.com/about" rel="nofollow noopener noreferrer">about</a><img src="https://example.com/posts/img/a.png"/><blockquote cite="https://example.com/source">q</blockquote>`,
+		},
+		{
+			name:  "protocol relative url resolved",
+			input: `<img src="//cdn.example.net/a.png">`,
+			want:  `<img src="https://cdn.example.net/a.png"/>`,
+		},
+		{
+			name:  "youtube embed kept",
+			input: `<iframe src="https://www.youtube.com/embed/abc" width="560" height="315" onload="alert(1)"></iframe>`,
+			want:  `<iframe width="560" height="315" src="https://www.youtube.com/embed/abc" allowfullscreen="" sandbox="allow-scripts allow-same-origin allow-presentation"></iframe>`,
+		},
+		{
+			name:  "vimeo embed kept",
+			input: `<iframe src="https://player.vimeo.com/video/123"></iframe>`,
+			want:  `<iframe src="https://player.vimeo.com/video/123" allowfullscreen="" sandbox="allow-scripts allow-same-origin allow-presentation"></iframe>`,
+		},
+		{
+			name:  "embed from other host dropped",
+			input: `<p>a</p><iframe src="https://evil.example/embed/abc"></iframe>`,
+			want:  `<p>a</p>`,
+		},
+		{
+			name:  "embed outside player path dropped",
+			input: `<iframe src="https://www.youtube.com/watch?v=abc"></iframe>`,
+			want:  ``,
+		},
+		{
+			name:  "plain http embed dropped",
+			input: `<iframe src="http://www.youtube.com/embed/abc"></iframe>`,
+			want:  ``,
+		},
+		{
+			name:  "javascript embed dropped",
+			input: `<iframe src="javascript:alert(1)"></iframe>`,
+			want:  ``,
+		},
+		{
+			name:  "comments dropped",
+			input: `<p>a<!-- secret --></p>`,
+			want:  `<p>a</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.input, base); got != tt.want {
+				t.Errorf("SanitizeHTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package helper
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qmranik/rss-aggregator-backend/models"
+)
+
+const (
+	// DefaultPollInterval is used when a feed gives no hint about its posting frequency.
+	DefaultPollInterval = time.Hour
+	// MinPollInterval is the shortest interval derived from a feed's posting frequency.
+	MinPollInterval = 10 * time.Minute
+	// MaxPollInterval is the longest interval derived from a feed's posting frequency.
+	MaxPollInterval = 24 * time.Hour
+
+	// MaxBackoffInterval caps the delay between retries of a failing feed.
+	MaxBackoffInterval = 7 * 24 * time.Hour
+
+	// frequencySampleSize is the number of most recent items used to estimate posting frequency.
+	frequencySampleSize = 10
+)
+
+// PollInterval estimates how often a feed should be polled from the publication dates
+// of its items, raised to honor the channel's <ttl> and the response's Cache-Control max-age.
+func PollInterval(feed *models.RSSFeed, cacheMaxAge time.Duration) time.Duration {
+	interval := postingInterval(feed.Channel.Item)
+
+	// <ttl> is the number of minutes the channel may be cached before refreshing
+	if ttl, err := strconv.Atoi(strings.TrimSpace(feed.Channel.TTL)); err == nil && ttl > 0 {
+		if ttlInterval := time.Duration(ttl) * time.Minute; ttlInterval > interval {
+			interval = ttlInterval
+		}
+	}
+
+	if cacheMaxAge > interval {
+		interval = cacheMaxAge
+	}
+
+	return interval
+}
+
+// postingInterval returns half the average gap between the most recent items,
+// clamped between MinPollInterval and MaxPollInterval.
+func postingInterval(items []models.RSSItem) time.Duration {
+	var dates []time.Time
+	for _, item := range items {
+		if t, _, ok := itemDate(item); ok {
+			dates = append(dates, t)
+		}
+	}
+	if len(dates) < 2 {
+		return DefaultPollInterval
+	}
+
+	// Newest first, then keep only the most recent sample
+	sort.Slice(dates, func(i, j int) bool { return dates[i].After(dates[j]) })
+	if len(dates) > frequencySampleSize {
+		dates = dates[:frequencySampleSize]
+	}
+
+	// Poll twice per average gap so new items are picked up reasonably soon
+	averageGap := dates[0].Sub(dates[len(dates)-1]) / time.Duration(len(dates)-1)
+	interval := averageGap / 2
+
+	if interval < MinPollInterval {
+		return MinPollInterval
+	}
+	if interval > MaxPollInterval {
+		return MaxPollInterval
+	}
+	return interval
+}
+
+// BackoffInterval doubles the polling interval for every consecutive failure,
+// capped at MaxBackoffInterval.
+func BackoffInterval(interval time.Duration, failures int32) time.Duration {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	for i := int32(1); i < failures; i++ {
+		interval *= 2
+		if interval >= MaxBackoffInterval {
+			return MaxBackoffInterval
+		}
+	}
+	return interval
+}
+
+// NextFetchAt returns the time a feed is next due, moving it past any hours and days
+// the channel asks aggregators to skip via <skipHours> and <skipDays>.
+func NextFetchAt(now time.Time, interval time.Duration, channel *models.RSSChannel) time.Time {
+	next := now.Add(interval).UTC()
+	if channel == nil {
+		return next
+	}
+
+	skipHours := make(map[int]bool)
+	for _, hour := range channel.SkipHours {
+		if h, err := strconv.Atoi(strings.TrimSpace(hour)); err == nil {
+			skipHours[h] = true
+		}
+	}
+	skipDays := make(map[string]bool)
+	for _, day := range channel.SkipDays {
+		skipDays[strings.ToLower(strings.TrimSpace(day))] = true
+	}
+
+	// Skip hours are expressed in GMT; bound the search to one week
+	for i := 0; i < 24*7; i++ {
+		if !skipHours[next.Hour()] && !skipDays[strings.ToLower(next.Weekday().String())] {
+			return next
+		}
+		next = next.Truncate(time.Hour).Add(time.Hour)
+	}
+	return now.Add(interval).UTC()
+}
+
+// cacheMaxAge returns the max-age directive of a Cache-Control header, or zero.
+func cacheMaxAge(header string) time.Duration {
+	for _, directive := range strings.Split(header, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(directive), "=")
+		if !found || !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 0
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func retryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -3,8 +3,7 @@ package helper
 import (
 	"context"
 	"database/sql"
-	"encoding/xml"
-	"io"
+	"errors"
 	"net/http"
 	"strings"
 	"sync"
@@ -16,132 +15,862 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// StartScraping initiates a periodic feed scraping process.
-// It fetches feeds every `timeBetweenRequest` duration using `concurrency` number of goroutines.
-func StartScraping(db *database.Queries, concurrency int, timeBetweenRequest time.Duration) {
-	log.Infof("Collecting feeds every %s using %v goroutines...", timeBetweenRequest, concurrency)
-	ticker := time.NewTicker(timeBetweenRequest)
+// Store persists posts and the fetch state of feeds. It is satisfied by *database.Queries.
+type Store interface {
+	ClaimFeedsToFetch(ctx context.Context, arg database.ClaimFeedsToFetchParams) ([]database.Feed, error)
+	ReleaseFeedLease(ctx context.Context, arg database.ReleaseFeedLeaseParams) error
+	ExtendFeedLease(ctx context.Context, arg database.ExtendFeedLeaseParams) error
+	UpdateFeedCacheHeaders(ctx context.Context, arg database.UpdateFeedCacheHeadersParams) error
+	ScheduleFeedFetch(ctx context.Context, arg database.ScheduleFeedFetchParams) error
+	RecordFeedSuccess(ctx context.Context, id uuid.UUID) error
+	RecordFeedFailure(ctx context.Context, arg database.RecordFeedFailureParams) (database.Feed, error)
+	DisableFeed(ctx context.Context, id uuid.UUID) error
+	MarkFeedBlockedByRobots(ctx context.Context, arg database.MarkFeedBlockedByRobotsParams) error
+	MarkFeedGone(ctx context.Context, arg database.MarkFeedGoneParams) error
+	GetFeedByURL(ctx context.Context, url string) (database.Feed, error)
+	UpdateFeedURL(ctx context.Context, arg database.UpdateFeedURLParams) error
+	MergeFeeds(ctx context.Context, arg database.MergeFeedsParams) error
+	AdoptPostGUID(ctx context.Context, arg database.AdoptPostGUIDParams) error
+	FinishPostGUIDAdoption(ctx context.Context, id uuid.UUID) error
+	UpsertPost(ctx context.Context, arg database.UpsertPostParams) (database.Post, error)
+	UpdatePostContent(ctx context.Context, arg database.UpdatePostContentParams) error
+	GetUnsanitizedPosts(ctx context.Context, limit int32) ([]database.Post, error)
+	UpdatePostSanitized(ctx context.Context, arg database.UpdatePostSanitizedParams) error
+	UpsertPostAttachment(ctx context.Context, arg database.UpsertPostAttachmentParams) error
+	DeletePostAttachments(ctx context.Context, postID uuid.UUID) error
+	UpsertTag(ctx context.Context, arg database.UpsertTagParams) (database.Tag, error)
+	AddPostTag(ctx context.Context, arg database.AddPostTagParams) error
+	DeletePostTags(ctx context.Context, postID uuid.UUID) error
+	CreateFeedFetch(ctx context.Context, arg database.CreateFeedFetchParams) error
+	PruneFeedFetches(ctx context.Context, arg database.PruneFeedFetchesParams) error
+	UpdateFeedMetadata(ctx context.Context, arg database.UpdateFeedMetadataParams) error
+	UpdateFeedIcon(ctx context.Context, arg database.UpdateFeedIconParams) error
+}
+
+// FeedLeaseDuration is how long a claimed feed is reserved for the worker that claimed it.
+// Leases of workers that crash mid-fetch expire after this long and the feed is claimed again,
+// while a worker still scraping the feed extends its lease every half FeedLeaseDuration.
+const FeedLeaseDuration = 10 * time.Minute
+
+// FeedFetchRetention is the number of fetch attempts kept in the history of each feed.
+const FeedFetchRetention = 100
+
+// sanitizeBatchSize is the number of stored posts sanitized per query by SanitizeStoredPosts.
+const sanitizeBatchSize = 500
+
+// postWriteTimeout bounds the writes storing a single post, its attachments and its tags.
+// They do not use the scraper's context, so a shutdown never leaves a post half stored.
+const postWriteTimeout = 10 * time.Second
+
+// HostBusyRetryDelay is how long a feed whose host was too busy to fetch it waits before the next attempt.
+const HostBusyRetryDelay = 5 * time.Minute
+
+// FeedIconRefreshInterval is how long the discovered icon of a feed's site is cached
+// before the site is checked again.
+const FeedIconRefreshInterval = 7 * 24 * time.Hour
+
+// Scraper periodically collects the feeds that are due and stores their posts.
+// Any number of scrapers with distinct WorkerIDs may share a database, each feed
+// is leased to a single worker while it is being fetched.
+type Scraper struct {
+	WorkerID     string            // WorkerID identifies this scraper in feed leases.
+	Store        Store             // Store persists posts and the fetch state of feeds.
+	Fetcher      Fetcher           // Fetcher retrieves and parses feed documents.
+	Articles     ArticleFetcher    // Articles, if set, extracts full content for feeds that ask for it.
+	Icons        IconFinder        // Icons, if set, discovers the site icon of feeds that do not advertise one.
+	Concurrency  int               // Concurrency is the maximum number of feeds fetched per batch.
+	Interval     time.Duration     // Interval is the time between batches.
+	DrainTimeout time.Duration     // DrainTimeout is how long the feeds being scraped may take to finish once Start's context is cancelled.
+	MaxFailures  int               // MaxFailures disables a feed after this many consecutive failures (0 never disables).
+	WebSub       *WebSubSubscriber // WebSub, if set, subscribes to the hubs of feeds that advertise one.
+	Credentials  *CredentialCipher // Credentials, if set, decrypts the credentials of private feeds.
+}
+
+// Start initiates a periodic feed scraping process.
+// Every Interval it fetches up to Concurrency feeds that are due, using one goroutine per feed.
+// Cancelling ctx stops claiming feeds, the feeds already claimed are still scraped for up to
+// DrainTimeout. It returns once the in-flight batch has finished.
+func (s *Scraper) Start(ctx context.Context) {
+	log.Infof("Collecting feeds every %s using %v goroutines...", s.Interval, s.Concurrency)
+	ticker := time.NewTicker(s.Interval)
 	defer ticker.Stop()
 
-	for ; ; <-ticker.C {
-		feeds, err := db.GetNextFeedsToFetch(context.Background(), int32(concurrency))
+	// Posts stored before HTML was sanitized on ingest are sanitized once
+	s.SanitizeStoredPosts(ctx)
+
+	for {
+		s.scrapeBatch(ctx)
+		if s.WebSub != nil {
+			s.WebSub.RenewLeases(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info("Stopped collecting feeds")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scrapeBatch claims the next feeds that are due, scrapes them and waits for all of them to finish.
+func (s *Scraper) scrapeBatch(ctx context.Context) {
+	// Claiming skips feeds leased by other workers and marks the claimed ones as fetched
+	feeds, err := s.Store.ClaimFeedsToFetch(ctx, database.ClaimFeedsToFetchParams{
+		WorkerID:     s.WorkerID,
+		LeaseSeconds: int32(FeedLeaseDuration / time.Second),
+		BatchSize:    int32(s.Concurrency),
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"workerID": s.WorkerID,
+			"error":    err,
+		}).Error("Couldn't claim feeds to fetch")
+		return
+	}
+	log.Infof("Claimed %v feeds to fetch!", len(feeds))
+
+	// The claimed feeds are finished when shutting down, unless that takes longer than DrainTimeout
+	drainCtx, cancel := drainContext(ctx, s.DrainTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, feed := range feeds {
+		wg.Add(1)
+		go func(feed database.Feed) {
+			defer wg.Done()
+			defer s.releaseLease(feed)
+			stop := s.keepLease(feed, FeedLeaseDuration/2)
+			defer stop()
+			s.ScrapeFeed(drainCtx, feed)
+		}(feed)
+	}
+	wg.Wait()
+}
+
+// drainContext returns a context that is not cancelled with ctx, but timeout after it.
+// Calling the returned cancel function releases its resources.
+func drainContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	drainCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-drainCtx.Done():
+			return
+		}
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-drainCtx.Done():
+		}
+	}()
+	return drainCtx, cancel
+}
+
+// SanitizeStoredPosts sanitizes the posts stored before HTML was sanitized on ingest,
+// in batches of sanitizeBatchSize. Until then the API sanitizes them when serving them.
+func (s *Scraper) SanitizeStoredPosts(ctx context.Context) {
+	total := 0
+	for ctx.Err() == nil {
+		posts, err := s.Store.GetUnsanitizedPosts(ctx, sanitizeBatchSize)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error": err,
-			}).Error("Couldn't get next feeds to fetch")
-			continue
+			}).Error("Couldn't get posts to sanitize")
+			return
+		}
+		if len(posts) == 0 {
+			break
 		}
-		log.Infof("Found %v feeds to fetch!", len(feeds))
 
-		var wg sync.WaitGroup
-		for _, feed := range feeds {
-			wg.Add(1)
-			go ScrapeFeed(db, &wg, feed)
+		for _, post := range posts {
+			post = SanitizeStoredPost(post)
+			err := s.Store.UpdatePostSanitized(ctx, database.UpdatePostSanitizedParams{
+				ID:          post.ID,
+				Description: post.Description,
+				Preview:     post.Preview,
+				Content:     post.Content,
+			})
+			if err != nil {
+				// Stop rather than fetch the same batch again
+				log.WithFields(log.Fields{
+					"postID": post.ID,
+					"error":  err,
+				}).Error("Couldn't store sanitized post")
+				return
+			}
 		}
-		wg.Wait()
+		total += len(posts)
+	}
+
+	if total > 0 {
+		log.Infof("Sanitized %v stored posts", total)
 	}
 }
 
-// ScrapeFeed scrapes a single feed and inserts its posts into the database.
-// It marks the feed as fetched before processing and handles potential errors.
-func ScrapeFeed(db *database.Queries, wg *sync.WaitGroup, feed database.Feed) {
-	defer wg.Done()
+// releaseLease hands a claimed feed back once it has been scraped. It runs even
+// when shutting down, so it does not use the scraper's context.
+func (s *Scraper) releaseLease(feed database.Feed) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	// Mark the feed as fetched
-	_, err := db.MarkFeedFetched(context.Background(), feed.ID)
+	err := s.Store.ReleaseFeedLease(ctx, database.ReleaseFeedLeaseParams{
+		ID:         feed.ID,
+		LeaseOwner: sql.NullString{String: s.WorkerID, Valid: true},
+	})
 	if err != nil {
 		log.WithFields(log.Fields{
 			"feedID":   feed.ID,
-			"feedName": feed.Name,
+			"workerID": s.WorkerID,
 			"error":    err,
-		}).Error("Couldn't mark feed fetched")
-		return
+		}).Error("Couldn't release feed lease")
+	}
+}
+
+// keepLease extends the lease of a claimed feed every interval, so that scrapes taking longer
+// than FeedLeaseDuration keep the feed to themselves. It stops once the returned function is called.
+func (s *Scraper) keepLease(feed database.Feed, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			// Like releasing the lease, extending it must not be cut short by a shutdown
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			err := s.Store.ExtendFeedLease(ctx, database.ExtendFeedLeaseParams{
+				LeaseSeconds: int32(FeedLeaseDuration / time.Second),
+				ID:           feed.ID,
+				WorkerID:     s.WorkerID,
+			})
+			cancel()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"feedID":   feed.ID,
+					"workerID": s.WorkerID,
+					"error":    err,
+				}).Error("Couldn't extend feed lease")
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// ScrapeFeed scrapes a single feed claimed by this worker and inserts its posts into the store.
+// It records the outcome of the fetch, backing off and eventually disabling the feed after
+// MaxFailures consecutive errors. Cancelling ctx aborts the fetch and stops inserting further posts.
+func (s *Scraper) ScrapeFeed(ctx context.Context, feed database.Feed) {
+	// Keep a record of the attempt in the feed's fetch history
+	started := time.Now()
+	fetch := database.CreateFeedFetchParams{
+		ID:        uuid.New(),
+		FeedID:    feed.ID,
+		FetchedAt: started.UTC(),
 	}
+	defer func() { s.recordFetch(ctx, fetch) }()
 
-	// Fetch and parse the feed data
-	feedData, err := FetchFeed(feed.Url)
+	// Fetch and parse the feed data, sending the validators from the previous fetch
+	// and the credentials of private feeds
+	var result *FetchResult
+	creds, err := s.feedCredentials(feed)
+	if err == nil {
+		result, err = s.Fetcher.FetchFeed(ctx, feed.Url, feed.Etag.String, feed.LastModified.String, creds)
+	}
+	fetch.DurationMs = int32(time.Since(started) / time.Millisecond)
 	if err != nil {
+		fetch.Error = sql.NullString{String: err.Error(), Valid: true}
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			fetch.StatusCode = sql.NullInt32{Int32: int32(statusErr.StatusCode), Valid: true}
+		}
+
+		// A shutdown is not the feed's fault
+		if ctx.Err() != nil {
+			return
+		}
+
+		// The request was never sent because other requests to the host were queued
+		// ahead of it, try again later without counting a failure
+		if errors.Is(err, ErrHostBusy) {
+			log.WithFields(log.Fields{
+				"feedID":   feed.ID,
+				"feedName": feed.Name,
+				"feedUrl":  feed.Url,
+			}).Info("Host busy, rescheduling feed")
+			s.scheduleFeed(ctx, feed, NextFetchAt(time.Now(), HostBusyRetryDelay, nil), feed.PollIntervalSeconds)
+			return
+		}
+
 		log.WithFields(log.Fields{
 			"feedID":   feed.ID,
 			"feedName": feed.Name,
 			"feedUrl":  feed.Url,
 			"error":    err,
 		}).Error("Couldn't collect feed")
+
+		// robots.txt forbids fetching the feed, which is not a failure of the feed itself
+		if errors.Is(err, ErrBlockedByRobots) {
+			s.recordBlockedByRobots(ctx, feed, err)
+			return
+		}
+
+		// The feed was removed for good, stop fetching it
+		if statusErr != nil && statusErr.StatusCode == http.StatusGone {
+			s.recordGone(ctx, feed, err)
+			return
+		}
+
+		s.recordFailure(ctx, feed, err)
 		return
 	}
+	fetch.StatusCode = sql.NullInt32{Int32: int32(result.StatusCode), Valid: true}
+	fetch.Bytes = result.Bytes
 
-	// Insert each post from the feed into the database
-	for _, item := range feedData.Channel.Item {
-		publishedAt, err := ParsePubDate(item.PubDate)
-		if err != nil {
+	// The fetch succeeded, reset the failure tracking
+	if err := s.Store.RecordFeedSuccess(ctx, feed.ID); err != nil {
+		log.WithFields(log.Fields{
+			"feedID":   feed.ID,
+			"feedName": feed.Name,
+			"error":    err,
+		}).Error("Couldn't record feed success")
+	}
+
+	// The feed moved permanently, remember its new URL
+	if result.PermanentURL != "" && result.PermanentURL != feed.Url {
+		var merged bool
+		feed, merged = s.moveFeed(ctx, feed, result.PermanentURL)
+		fetch.FeedID = feed.ID
+		if merged {
+			return
+		}
+	}
+
+	// Nothing to insert when the feed has not changed since the last fetch
+	if result.NotModified {
+		interval := time.Duration(feed.PollIntervalSeconds) * time.Second
+		if result.CacheMaxAge > interval {
+			interval = result.CacheMaxAge
+		}
+		s.scheduleFeed(ctx, feed, NextFetchAt(time.Now(), interval, nil), feed.PollIntervalSeconds)
+		log.Infof("Feed %s not modified since last fetch", feed.Name)
+		return
+	}
+
+	feedData := result.Feed
+	s.refreshMetadata(ctx, feed, &feedData.Channel)
+
+	// Adapt the polling schedule to the feed's observed posting frequency,
+	// polling only as a safety net while a WebSub hub pushes new content
+	interval := PollInterval(feedData, result.CacheMaxAge)
+	// Hubs cannot fetch private feeds, keep polling them
+	if s.WebSub != nil && !feed.IsPrivate && s.WebSub.Discover(ctx, feed, result.Hubs, result.Topic) {
+		interval = MaxPollInterval
+	}
+	s.scheduleFeed(ctx, feed, NextFetchAt(time.Now(), interval, &feedData.Channel), int32(interval/time.Second))
+
+	inserted, _, err := s.IngestFeed(ctx, feed, feedData)
+	fetch.ItemsSeen = int32(len(feedData.Channel.Item))
+	fetch.ItemsInserted = int32(inserted)
+	if err != nil {
+		// Without the validators the next fetch gets the whole document again
+		// and stores the items that were missed
+		return
+	}
+
+	// Remember the validators for the next conditional fetch
+	err = s.Store.UpdateFeedCacheHeaders(ctx, database.UpdateFeedCacheHeadersParams{
+		ID:           feed.ID,
+		Etag:         sql.NullString{String: result.ETag, Valid: result.ETag != ""},
+		LastModified: sql.NullString{String: result.LastModified, Valid: result.LastModified != ""},
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedID":   feed.ID,
+			"feedName": feed.Name,
+			"error":    err,
+		}).Error("Couldn't update feed cache headers")
+	}
+
+	// Every item has been stored once, posts stored by link that were not adopted
+	// belong to items no longer in the feed
+	if feed.AdoptPostGuids {
+		if err := s.Store.FinishPostGUIDAdoption(ctx, feed.ID); err != nil {
 			log.WithFields(log.Fields{
-				"pubDate":  item.PubDate,
 				"feedID":   feed.ID,
 				"feedName": feed.Name,
 				"error":    err,
-			}).Error("Couldn't parse Published Date")
-			continue
+			}).Error("Couldn't finish post GUID adoption")
 		}
+	}
+}
 
-		_, err = db.CreatePost(context.Background(), database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now().UTC(),
-			UpdatedAt:   time.Now().UTC(),
-			FeedID:      feed.ID,
-			Title:       item.Title,
-			Description: sql.NullString{String: item.Description, Valid: true},
-			Url:         item.Link,
-			PublishedAt: publishedAt,
+// IngestFeed inserts new posts of a fetched or pushed feed document and updates
+// changed ones, identified per feed by GUID. It returns the number of inserted and updated posts,
+// and an error if ctx was cancelled or any item could not be stored completely.
+func (s *Scraper) IngestFeed(ctx context.Context, feed database.Feed, feedData *models.RSSFeed) (inserted, updated int, err error) {
+	for _, item := range feedData.Channel.Item {
+		// Stop between items when shutting down
+		if ctx.Err() != nil {
+			log.Infof("Feed %s collection interrupted", feed.Name)
+			return inserted, updated, ctx.Err()
+		}
+
+		// Items without a GUID are identified by their link
+		guid := strings.TrimSpace(item.GUID)
+		if guid == "" {
+			guid = item.Link
+		}
+
+		// The post and its attachments and tags are stored together even when shutting down
+		writeCtx, cancel := context.WithTimeout(context.Background(), postWriteTimeout)
+
+		// Posts stored before GUIDs were tracked are identified by their link,
+		// take them over instead of inserting the item again
+		if feed.AdoptPostGuids && guid != item.Link {
+			err := s.Store.AdoptPostGUID(writeCtx, database.AdoptPostGUIDParams{
+				FeedID: feed.ID,
+				Url:    item.Link,
+				Guid:   guid,
+			})
+			if err != nil {
+				log.WithFields(log.Fields{
+					"feedID": feed.ID,
+					"guid":   guid,
+					"error":  err,
+				}).Error("Couldn't adopt post GUID")
+			}
+		}
+
+		// Feed markup is untrusted, sanitize it before it reaches any client
+		description := SanitizeHTML(item.Description, item.Link)
+		author := ItemAuthor(item)
+		commentsURL := ItemCommentsURL(item)
+
+		// Items without a usable date are dated when first seen, which an
+		// existing post keeps on later fetches
+		now := time.Now().UTC()
+		publishedAt, dateSource := ItemPublishedAt(item, now)
+
+		postID := uuid.New()
+		// Attachments and tags are part of the change check, so edits of only them update the post
+		attachments := ItemAttachments(postID, item, now)
+		tags := ItemCategories(item)
+		post, upsertErr := s.Store.UpsertPost(writeCtx, database.UpsertPostParams{
+			ID:              postID,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+			FeedID:          feed.ID,
+			Title:           PlainText(item.Title),
+			Description:     sql.NullString{String: description, Valid: true},
+			Url:             item.Link,
+			PublishedAt:     sql.NullTime{Time: publishedAt, Valid: true},
+			Guid:            guid,
+			Preview:         sql.NullString{String: Preview(description), Valid: true},
+			Author:          sql.NullString{String: author, Valid: author != ""},
+			CommentsUrl:     sql.NullString{String: commentsURL, Valid: commentsURL != ""},
+			DateSource:      dateSource,
+			AttachmentsHash: nullString(AttachmentsHash(attachments)),
+			TagsHash:        nullString(TagsHash(tags)),
 		})
-		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-				// Skip duplicate entries
+		if upsertErr != nil {
+			cancel()
+			if errors.Is(upsertErr, sql.ErrNoRows) {
+				// The post exists and is unchanged
 				continue
 			}
 			log.WithFields(log.Fields{
 				"feedID":   feed.ID,
 				"feedName": feed.Name,
 				"title":    item.Title,
+				"error":    upsertErr,
+			}).Error("Couldn't upsert post")
+			err = upsertErr
+			continue
+		}
+
+		if attachErr := s.storeAttachments(writeCtx, feed, post.ID, attachments); attachErr != nil {
+			err = attachErr
+		}
+		if tagErr := s.storeTags(writeCtx, feed, post.ID, tags, now); tagErr != nil {
+			err = tagErr
+		}
+		cancel()
+
+		if post.ID == postID {
+			inserted++
+			if feed.FetchFullContent && s.Articles != nil {
+				s.fetchFullContent(ctx, feed, post)
+			}
+		} else {
+			updated++
+		}
+	}
+
+	log.Infof("Feed %s collected, %v posts found, %v new, %v updated", feed.Name, len(feedData.Channel.Item), inserted, updated)
+	return inserted, updated, err
+}
+
+// feedCredentials decrypts the credentials of a private feed, returning nil for feeds without any.
+func (s *Scraper) feedCredentials(feed database.Feed) (*models.FeedCredentials, error) {
+	if len(feed.Credentials) == 0 {
+		return nil, nil
+	}
+	if s.Credentials == nil {
+		return nil, ErrCredentialsUnavailable
+	}
+	return s.Credentials.Decrypt(feed.ID, feed.Credentials)
+}
+
+// recordFetch adds a fetch attempt to the feed's history, dropping the oldest
+// attempts beyond FeedFetchRetention. Attempts interrupted by a shutdown are not recorded.
+func (s *Scraper) recordFetch(ctx context.Context, fetch database.CreateFeedFetchParams) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err := s.Store.CreateFeedFetch(ctx, fetch); err != nil {
+		log.WithFields(log.Fields{
+			"feedID": fetch.FeedID,
+			"error":  err,
+		}).Error("Couldn't record feed fetch")
+		return
+	}
+
+	err := s.Store.PruneFeedFetches(ctx, database.PruneFeedFetchesParams{
+		FeedID: fetch.FeedID,
+		Limit:  FeedFetchRetention,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedID": fetch.FeedID,
+			"error":  err,
+		}).Error("Couldn't prune feed fetch history")
+	}
+}
+
+// refreshMetadata stores the title, description, site link, language and image of a
+// fetched feed's channel, then refreshes the feed's icon.
+func (s *Scraper) refreshMetadata(ctx context.Context, feed database.Feed, channel *models.RSSChannel) {
+	siteURL := absoluteURL(feed.Url, channel.Link)
+	err := s.Store.UpdateFeedMetadata(ctx, database.UpdateFeedMetadataParams{
+		ID:          feed.ID,
+		Title:       nullString(PlainText(channel.Title)),
+		Description: nullString(PlainText(channel.Description)),
+		SiteUrl:     nullString(siteURL),
+		Language:    nullString(channel.Language),
+		ImageUrl:    nullString(absoluteURL(feed.Url, channel.ImageURL())),
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedID":   feed.ID,
+			"feedName": feed.Name,
+			"error":    err,
+		}).Error("Couldn't update feed metadata")
+	}
+
+	s.refreshIcon(ctx, feed, channel, siteURL)
+}
+
+// refreshIcon stores the icon of a feed. An icon advertised by the feed itself is used
+// as is, otherwise the icon of the feed's site is discovered at most once per
+// FeedIconRefreshInterval, including when none was found.
+func (s *Scraper) refreshIcon(ctx context.Context, feed database.Feed, channel *models.RSSChannel, siteURL string) {
+	now := time.Now().UTC()
+	icon := absoluteURL(feed.Url, channel.Icon)
+	switch {
+	case icon != "":
+		if icon == feed.IconUrl.String {
+			return
+		}
+
+	case s.Icons == nil:
+		return
+
+	case feed.IconCheckedAt.Valid && now.Sub(feed.IconCheckedAt.Time) < FeedIconRefreshInterval:
+		return
+
+	default:
+		// Without a site link, look for the icon at the root of the feed's host
+		if siteURL == "" {
+			siteURL = absoluteURL(feed.Url, "/")
+		}
+		found, err := s.Icons.FindIcon(ctx, siteURL)
+		switch {
+		case err == nil:
+			icon = found
+		case errors.Is(err, ErrNoIconFound):
+			// The site has no icon, remember that it was checked
+		case ctx.Err() != nil:
+			return
+		default:
+			// Keep the previous icon while the site is unreachable
+			icon = feed.IconUrl.String
+		}
+	}
+
+	err := s.Store.UpdateFeedIcon(ctx, database.UpdateFeedIconParams{
+		ID:            feed.ID,
+		IconUrl:       nullString(icon),
+		IconCheckedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedID":   feed.ID,
+			"feedName": feed.Name,
+			"error":    err,
+		}).Error("Couldn't update feed icon")
+	}
+}
+
+// recordFailure counts a failed fetch, backs the feed off exponentially and
+// disables it once it has failed MaxFailures times in a row.
+func (s *Scraper) recordFailure(ctx context.Context, feed database.Feed, fetchErr error) {
+	updated, err := s.Store.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+		ID:        feed.ID,
+		LastError: sql.NullString{String: fetchErr.Error(), Valid: true},
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedID":   feed.ID,
+			"feedName": feed.Name,
+			"error":    err,
+		}).Error("Couldn't record feed failure")
+		return
+	}
+
+	if s.MaxFailures > 0 && updated.ConsecutiveFailures >= int32(s.MaxFailures) {
+		log.WithFields(log.Fields{
+			"feedID":   feed.ID,
+			"feedName": feed.Name,
+			"failures": updated.ConsecutiveFailures,
+		}).Warn("Disabling feed after repeated failures")
+		if err := s.Store.DisableFeed(ctx, feed.ID); err != nil {
+			log.WithFields(log.Fields{
+				"feedID":   feed.ID,
+				"feedName": feed.Name,
 				"error":    err,
-			}).Error("Couldn't create post")
+			}).Error("Couldn't disable feed")
 		}
+		return
+	}
+
+	// Back off exponentially, honoring a longer Retry-After sent with the error
+	interval := BackoffInterval(time.Duration(feed.PollIntervalSeconds)*time.Second, updated.ConsecutiveFailures)
+	var statusErr *StatusError
+	if errors.As(fetchErr, &statusErr) && statusErr.RetryAfter > interval {
+		interval = statusErr.RetryAfter
+	}
+	s.scheduleFeed(ctx, feed, NextFetchAt(time.Now(), interval, nil), feed.PollIntervalSeconds)
+}
+
+// storeAttachments replaces the enclosures, media and artwork of a new or changed post with the item's attachments.
+// It returns the last error encountered, after trying to store every attachment.
+func (s *Scraper) storeAttachments(ctx context.Context, feed database.Feed, postID uuid.UUID, attachments []database.UpsertPostAttachmentParams) error {
+	if err := s.Store.DeletePostAttachments(ctx, postID); err != nil {
+		log.WithFields(log.Fields{
+			"feedID": feed.ID,
+			"postID": postID,
+			"error":  err,
+		}).Error("Couldn't clear post attachments")
+		return err
+	}
+
+	var lastErr error
+	for _, attachment := range attachments {
+		// The item is stored as an existing post when it changed
+		attachment.PostID = postID
+		if err := s.Store.UpsertPostAttachment(ctx, attachment); err != nil {
+			log.WithFields(log.Fields{
+				"feedID": feed.ID,
+				"postID": postID,
+				"url":    attachment.Url,
+				"error":  err,
+			}).Error("Couldn't store post attachment")
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// storeTags replaces the tags of a new or changed post with the item's categories.
+// It returns the last error encountered, after trying to store every tag.
+func (s *Scraper) storeTags(ctx context.Context, feed database.Feed, postID uuid.UUID, tags []string, now time.Time) error {
+	if err := s.Store.DeletePostTags(ctx, postID); err != nil {
+		log.WithFields(log.Fields{
+			"feedID": feed.ID,
+			"postID": postID,
+			"error":  err,
+		}).Error("Couldn't clear post tags")
+		return err
 	}
 
-	log.Infof("Feed %s collected, %v posts found", feed.Name, len(feedData.Channel.Item))
+	var lastErr error
+
+	for _, name := range tags {
+		tag, err := s.Store.UpsertTag(ctx, database.UpsertTagParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			Name:      name,
+		})
+		if err == nil {
+			err = s.Store.AddPostTag(ctx, database.AddPostTagParams{PostID: postID, TagID: tag.ID})
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"feedID": feed.ID,
+				"postID": postID,
+				"tag":    name,
+				"error":  err,
+			}).Error("Couldn't store post tag")
+			lastErr = err
+		}
+	}
+	return lastErr
 }
 
-// FetchFeed retrieves and parses an RSS feed from the specified URL.
-// It returns the parsed feed or an error if fetching or parsing fails.
-func FetchFeed(feedURL string) (*models.RSSFeed, error) {
-	httpClient := http.Client{Timeout: 10 * time.Second}
-	resp, err := httpClient.Get(feedURL)
+// moveFeed updates the URL of a feed that moved permanently. When another public feed
+// already uses the new URL, the feed's follows and posts are merged into it and the feed
+// is deleted, which is reported by returning true. Private feeds are never merged.
+func (s *Scraper) moveFeed(ctx context.Context, feed database.Feed, newURL string) (database.Feed, bool) {
+	var existing database.Feed
+	err := sql.ErrNoRows
+	if !feed.IsPrivate {
+		existing, err = s.Store.GetFeedByURL(ctx, newURL)
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = s.Store.UpdateFeedURL(ctx, database.UpdateFeedURLParams{ID: feed.ID, Url: newURL})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"feedID": feed.ID,
+				"newUrl": newURL,
+				"error":  err,
+			}).Error("Couldn't update moved feed URL")
+			return feed, false
+		}
+		log.WithFields(log.Fields{
+			"feedID": feed.ID,
+			"oldUrl": feed.Url,
+			"newUrl": newURL,
+		}).Info("Feed moved permanently")
+		feed.Url = newURL
+		return feed, false
+
+	case err != nil:
+		log.WithFields(log.Fields{
+			"feedID": feed.ID,
+			"newUrl": newURL,
+			"error":  err,
+		}).Error("Couldn't look up moved feed URL")
+		return feed, false
+	}
+
+	err = s.Store.MergeFeeds(ctx, database.MergeFeedsParams{TargetID: existing.ID, SourceID: feed.ID})
 	if err != nil {
 		log.WithFields(log.Fields{
-			"feedURL": feedURL,
-			"error":   err,
-		}).Error("Failed to fetch feed")
-		return nil, err
+			"feedID":   feed.ID,
+			"targetID": existing.ID,
+			"error":    err,
+		}).Error("Couldn't merge moved feed")
+		return feed, false
 	}
-	defer resp.Body.Close()
+	log.WithFields(log.Fields{
+		"feedID":   feed.ID,
+		"targetID": existing.ID,
+		"newUrl":   newURL,
+	}).Info("Feed moved to the URL of an existing feed, merged them")
+	return existing, true
+}
 
-	dat, err := io.ReadAll(resp.Body)
+// recordGone disables a feed whose server answered 410 Gone.
+func (s *Scraper) recordGone(ctx context.Context, feed database.Feed, fetchErr error) {
+	err := s.Store.MarkFeedGone(ctx, database.MarkFeedGoneParams{
+		ID:        feed.ID,
+		LastError: sql.NullString{String: fetchErr.Error(), Valid: true},
+	})
 	if err != nil {
 		log.WithFields(log.Fields{
-			"feedURL": feedURL,
-			"error":   err,
-		}).Error("Failed to read response body")
-		return nil, err
+			"feedID":   feed.ID,
+			"feedName": feed.Name,
+			"error":    err,
+		}).Error("Couldn't mark feed gone")
+		return
 	}
+	log.WithFields(log.Fields{
+		"feedID":   feed.ID,
+		"feedName": feed.Name,
+	}).Warn("Feed is gone, no longer fetching it")
+}
 
-	var rssFeed models.RSSFeed
-	err = xml.Unmarshal(dat, &rssFeed)
+// fetchFullContent downloads the article behind a post and stores its readable content.
+func (s *Scraper) fetchFullContent(ctx context.Context, feed database.Feed, post database.Post) {
+	content, err := s.Articles.FetchArticle(ctx, post.Url)
 	if err != nil {
 		log.WithFields(log.Fields{
-			"feedURL": feedURL,
-			"error":   err,
-		}).Error("Failed to unmarshal XML")
-		return nil, err
+			"feedID": feed.ID,
+			"postID": post.ID,
+			"url":    post.Url,
+			"error":  err,
+		}).Warn("Couldn't fetch full article content")
+		return
 	}
 
-	return &rssFeed, nil
+	err = s.Store.UpdatePostContent(ctx, database.UpdatePostContentParams{
+		ID:      post.ID,
+		Content: sql.NullString{String: SanitizeHTML(content, post.Url), Valid: true},
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedID": feed.ID,
+			"postID": post.ID,
+			"error":  err,
+		}).Error("Couldn't store full article content")
+	}
+}
+
+// recordBlockedByRobots flags a feed as blocked by robots.txt and checks it again
+// after the longest polling interval, when the cached robots.txt has expired.
+func (s *Scraper) recordBlockedByRobots(ctx context.Context, feed database.Feed, fetchErr error) {
+	err := s.Store.MarkFeedBlockedByRobots(ctx, database.MarkFeedBlockedByRobotsParams{
+		ID:        feed.ID,
+		LastError: sql.NullString{String: fetchErr.Error(), Valid: true},
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedID":   feed.ID,
+			"feedName": feed.Name,
+			"error":    err,
+		}).Error("Couldn't mark feed blocked by robots.txt")
+	}
+	s.scheduleFeed(ctx, feed, NextFetchAt(time.Now(), MaxPollInterval, nil), feed.PollIntervalSeconds)
+}
+
+// scheduleFeed stores when a feed is next due and the polling interval it was derived from.
+func (s *Scraper) scheduleFeed(ctx context.Context, feed database.Feed, nextFetchAt time.Time, intervalSeconds int32) {
+	err := s.Store.ScheduleFeedFetch(ctx, database.ScheduleFeedFetchParams{
+		ID:                  feed.ID,
+		NextFetchAt:         sql.NullTime{Time: nextFetchAt, Valid: true},
+		PollIntervalSeconds: intervalSeconds,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"feedID":   feed.ID,
+			"feedName": feed.Name,
+			"error":    err,
+		}).Error("Couldn't schedule next feed fetch")
+	}
 }
@@ -0,0 +1,897 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qmranik/rss-aggregator-backend/internal/database"
+	"github.com/qmranik/rss-aggregator-backend/models"
+)
+
+// memStore is an in-memory Store that keeps posts per feed and GUID and records
+// the fetch state reported by the scraper.
+type memStore struct {
+	mu          sync.Mutex
+	posts       map[string]database.Post // keyed by feed ID and GUID
+	upsertErr   error                    // upsertErr, if set, fails every UpsertPost
+	afterUpsert func()                   // afterUpsert, if set, runs after every UpsertPost
+	validators  map[uuid.UUID]database.UpdateFeedCacheHeadersParams
+	attachments map[uuid.UUID][]database.UpsertPostAttachmentParams
+	tags        map[uuid.UUID][]string
+	tagNames    map[uuid.UUID]string
+	fetches     []database.CreateFeedFetchParams
+	schedules   []database.ScheduleFeedFetchParams
+	failures    map[uuid.UUID]int32
+	lastErrors  map[uuid.UUID]string
+	successes   int
+	extensions  int
+	adoptions   int
+	adopted     map[uuid.UUID]bool // feeds done adopting posts stored by link
+	disabled    map[uuid.UUID]bool
+	gone        map[uuid.UUID]bool
+	blocked     map[uuid.UUID]bool
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		posts:       make(map[string]database.Post),
+		validators:  make(map[uuid.UUID]database.UpdateFeedCacheHeadersParams),
+		attachments: make(map[uuid.UUID][]database.UpsertPostAttachmentParams),
+		tags:        make(map[uuid.UUID][]string),
+		tagNames:    make(map[uuid.UUID]string),
+		failures:    make(map[uuid.UUID]int32),
+		lastErrors:  make(map[uuid.UUID]string),
+		disabled:    make(map[uuid.UUID]bool),
+		gone:        make(map[uuid.UUID]bool),
+		blocked:     make(map[uuid.UUID]bool),
+		adopted:     make(map[uuid.UUID]bool),
+	}
+}
+
+func (m *memStore) ClaimFeedsToFetch(ctx context.Context, arg database.ClaimFeedsToFetchParams) ([]database.Feed, error) {
+	return nil, nil
+}
+
+func (m *memStore) ReleaseFeedLease(ctx context.Context, arg database.ReleaseFeedLeaseParams) error {
+	return nil
+}
+
+func (m *memStore) ExtendFeedLease(ctx context.Context, arg database.ExtendFeedLeaseParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.extensions++
+	return nil
+}
+
+func (m *memStore) UpdateFeedCacheHeaders(ctx context.Context, arg database.UpdateFeedCacheHeadersParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.validators[arg.ID] = arg
+	return nil
+}
+
+func (m *memStore) ScheduleFeedFetch(ctx context.Context, arg database.ScheduleFeedFetchParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.schedules = append(m.schedules, arg)
+	return nil
+}
+
+func (m *memStore) RecordFeedSuccess(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.successes++
+	m.failures[id] = 0
+	return nil
+}
+
+func (m *memStore) RecordFeedFailure(ctx context.Context, arg database.RecordFeedFailureParams) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures[arg.ID]++
+	m.lastErrors[arg.ID] = arg.LastError.String
+	return database.Feed{ID: arg.ID, ConsecutiveFailures: m.failures[arg.ID], LastError: arg.LastError}, nil
+}
+
+func (m *memStore) DisableFeed(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.disabled[id] = true
+	return nil
+}
+
+func (m *memStore) MarkFeedBlockedByRobots(ctx context.Context, arg database.MarkFeedBlockedByRobotsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blocked[arg.ID] = true
+	return nil
+}
+
+func (m *memStore) MarkFeedGone(ctx context.Context, arg database.MarkFeedGoneParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gone[arg.ID] = true
+	return nil
+}
+
+func (m *memStore) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (m *memStore) UpdateFeedURL(ctx context.Context, arg database.UpdateFeedURLParams) error {
+	return nil
+}
+
+func (m *memStore) MergeFeeds(ctx context.Context, arg database.MergeFeedsParams) error {
+	return nil
+}
+
+// AdoptPostGUID mirrors the AdoptPostGUID query, rekeying a post identified by its link.
+func (m *memStore) AdoptPostGUID(ctx context.Context, arg database.AdoptPostGUIDParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.adoptions++
+
+	if _, ok := m.posts[arg.FeedID.String()+"|"+arg.Guid]; ok {
+		return nil
+	}
+	oldKey := arg.FeedID.String() + "|" + arg.Url
+	post, ok := m.posts[oldKey]
+	if !ok || post.Url != arg.Url {
+		return nil
+	}
+	delete(m.posts, oldKey)
+	post.Guid = arg.Guid
+	m.posts[arg.FeedID.String()+"|"+arg.Guid] = post
+	return nil
+}
+
+func (m *memStore) FinishPostGUIDAdoption(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.adopted[id] = true
+	return nil
+}
+
+// UpsertPost mirrors the UpsertPost query: a new GUID inserts a post, a changed post
+// is updated in place and an unchanged post yields sql.ErrNoRows.
+func (m *memStore) UpsertPost(ctx context.Context, arg database.UpsertPostParams) (database.Post, error) {
+	if m.afterUpsert != nil {
+		defer m.afterUpsert()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.upsertErr != nil {
+		return database.Post{}, m.upsertErr
+	}
+
+	key := arg.FeedID.String() + "|" + arg.Guid
+	existing, ok := m.posts[key]
+	if !ok {
+		post := database.Post{
+			ID:              arg.ID,
+			CreatedAt:       arg.CreatedAt,
+			UpdatedAt:       arg.UpdatedAt,
+			Title:           arg.Title,
+			Url:             arg.Url,
+			Description:     arg.Description,
+			PublishedAt:     arg.PublishedAt,
+			FeedID:          arg.FeedID,
+			Guid:            arg.Guid,
+			Preview:         arg.Preview,
+			Author:          arg.Author,
+			CommentsUrl:     arg.CommentsUrl,
+			DateSource:      arg.DateSource,
+			Sanitized:       true,
+			AttachmentsHash: arg.AttachmentsHash,
+			TagsHash:        arg.TagsHash,
+		}
+		m.posts[key] = post
+		return post, nil
+	}
+
+	firstSeen := arg.DateSource == DateSourceFirstSeen
+	changed := existing.Title != arg.Title ||
+		existing.Url != arg.Url ||
+		existing.Description != arg.Description ||
+		(!firstSeen && !existing.PublishedAt.Time.Equal(arg.PublishedAt.Time)) ||
+		existing.Author != arg.Author ||
+		existing.CommentsUrl != arg.CommentsUrl ||
+		existing.AttachmentsHash != arg.AttachmentsHash ||
+		existing.TagsHash != arg.TagsHash
+	if !changed {
+		return database.Post{}, sql.ErrNoRows
+	}
+
+	existing.Title = arg.Title
+	existing.Url = arg.Url
+	existing.Description = arg.Description
+	existing.Preview = arg.Preview
+	existing.Author = arg.Author
+	existing.CommentsUrl = arg.CommentsUrl
+	existing.AttachmentsHash = arg.AttachmentsHash
+	existing.TagsHash = arg.TagsHash
+	existing.UpdatedAt = arg.UpdatedAt
+	if !firstSeen {
+		existing.PublishedAt = arg.PublishedAt
+		existing.DateSource = arg.DateSource
+	}
+	m.posts[key] = existing
+	return existing, nil
+}
+
+func (m *memStore) UpdatePostContent(ctx context.Context, arg database.UpdatePostContentParams) error {
+	return nil
+}
+
+func (m *memStore) GetUnsanitizedPosts(ctx context.Context, limit int32) ([]database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var posts []database.Post
+	for _, post := range m.posts {
+		if !post.Sanitized && len(posts) < int(limit) {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+func (m *memStore) UpdatePostSanitized(ctx context.Context, arg database.UpdatePostSanitizedParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, post := range m.posts {
+		if post.ID == arg.ID {
+			post.Description = arg.Description
+			post.Preview = arg.Preview
+			post.Content = arg.Content
+			post.Sanitized = true
+			m.posts[key] = post
+		}
+	}
+	return nil
+}
+
+func (m *memStore) UpsertPostAttachment(ctx context.Context, arg database.UpsertPostAttachmentParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attachments[arg.PostID] = append(m.attachments[arg.PostID], arg)
+	return nil
+}
+
+func (m *memStore) DeletePostAttachments(ctx context.Context, postID uuid.UUID) error {
+	// Like the database, refuse to write once ctx is cancelled
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.attachments, postID)
+	return nil
+}
+
+func (m *memStore) UpsertTag(ctx context.Context, arg database.UpsertTagParams) (database.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tagNames[arg.ID] = arg.Name
+	return database.Tag{ID: arg.ID, CreatedAt: arg.CreatedAt, Name: arg.Name}, nil
+}
+
+func (m *memStore) AddPostTag(ctx context.Context, arg database.AddPostTagParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tags[arg.PostID] = append(m.tags[arg.PostID], m.tagNames[arg.TagID])
+	return nil
+}
+
+func (m *memStore) DeletePostTags(ctx context.Context, postID uuid.UUID) error {
+	// Like the database, refuse to write once ctx is cancelled
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tags, postID)
+	return nil
+}
+
+func (m *memStore) CreateFeedFetch(ctx context.Context, arg database.CreateFeedFetchParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fetches = append(m.fetches, arg)
+	return nil
+}
+
+func (m *memStore) PruneFeedFetches(ctx context.Context, arg database.PruneFeedFetchesParams) error {
+	return nil
+}
+
+func (m *memStore) UpdateFeedMetadata(ctx context.Context, arg database.UpdateFeedMetadataParams) error {
+	return nil
+}
+
+func (m *memStore) UpdateFeedIcon(ctx context.Context, arg database.UpdateFeedIconParams) error {
+	return nil
+}
+
+// postsByGUID returns the stored posts of a feed keyed by GUID.
+func (m *memStore) postsByGUID(feedID uuid.UUID) map[string]database.Post {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	posts := make(map[string]database.Post)
+	for _, post := range m.posts {
+		if post.FeedID == feedID {
+			posts[post.Guid] = post
+		}
+	}
+	return posts
+}
+
+// lastFetch returns the most recently recorded fetch attempt.
+func (m *memStore) lastFetch(t *testing.T) database.CreateFeedFetchParams {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.fetches) == 0 {
+		t.Fatal("no fetch recorded")
+	}
+	return m.fetches[len(m.fetches)-1]
+}
+
+// newTestScraper returns a scraper backed by a memStore that fetches feeds over
+// a plain client, so it can reach httptest servers.
+func newTestScraper() (*Scraper, *memStore) {
+	store := newMemStore()
+	return &Scraper{
+		WorkerID:    "test",
+		Store:       store,
+		Fetcher:     &HTTPFetcher{Client: &http.Client{Timeout: 5 * time.Second}},
+		Concurrency: 1,
+		Interval:    time.Minute,
+		MaxFailures: 3,
+	}, store
+}
+
+// serveFeed returns a server answering every request with an RSS document.
+func serveFeed(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// serveStatus returns a server answering every request with status.
+func serveStatus(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testFeed(url string) database.Feed {
+	return database.Feed{
+		ID:                  uuid.New(),
+		Name:                "Test feed",
+		Url:                 url,
+		PollIntervalSeconds: 3600,
+	}
+}
+
+const duplicatesFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+<title>Duplicates</title>
+<link>https://example.com/</link>
+<item>
+  <title>First</title>
+  <link>https://example.com/1</link>
+  <guid>post-1</guid>
+  <pubDate>Mon, 02 Jan 2006 15:04:05 +0000</pubDate>
+  <category>Go</category>
+</item>
+<item>
+  <title>First again</title>
+  <link>https://example.com/1</link>
+  <guid>post-1</guid>
+  <pubDate>Mon, 02 Jan 2006 15:04:05 +0000</pubDate>
+</item>
+<item>
+  <title>No guid</title>
+  <link>https://example.com/2</link>
+  <pubDate>Tue, 03 Jan 2006 15:04:05 +0000</pubDate>
+</item>
+</channel></rss>`
+
+func TestScrapeFeedDuplicates(t *testing.T) {
+	scraper, store := newTestScraper()
+	feed := testFeed(serveFeed(t, duplicatesFeed).URL)
+	ctx := context.Background()
+
+	scraper.ScrapeFeed(ctx, feed)
+
+	posts := store.postsByGUID(feed.ID)
+	if len(posts) != 2 {
+		t.Fatalf("stored %d posts, want 2", len(posts))
+	}
+	// A repeated GUID updates the post stored for its first occurrence
+	if got := posts["post-1"].Title; got != "First again" {
+		t.Errorf("post-1 title = %q, want %q", got, "First again")
+	}
+	// Items without a GUID are identified by their link
+	if _, ok := posts["https://example.com/2"]; !ok {
+		t.Errorf("item without a guid not stored by link, got %v", posts)
+	}
+	fetch := store.lastFetch(t)
+	if fetch.ItemsSeen != 3 || fetch.ItemsInserted != 2 {
+		t.Errorf("fetch saw %d items and inserted %d, want 3 and 2", fetch.ItemsSeen, fetch.ItemsInserted)
+	}
+
+	// Fetching the same document again changes nothing
+	scraper.ScrapeFeed(ctx, feed)
+	if got := len(store.postsByGUID(feed.ID)); got != 2 {
+		t.Errorf("stored %d posts after refetch, want 2", got)
+	}
+	if fetch := store.lastFetch(t); fetch.ItemsInserted != 0 {
+		t.Errorf("refetch inserted %d posts, want 0", fetch.ItemsInserted)
+	}
+}
+
+const badDatesFeed = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel>
+<title>Bad dates</title>
+<item>
+  <title>Garbage date</title>
+  <link>https://example.com/garbage</link>
+  <pubDate>sometime last week</pubDate>
+</item>
+<item>
+  <title>Undated</title>
+  <link>https://example.com/undated</link>
+</item>
+<item>
+  <title>Dublin Core date</title>
+  <link>https://example.com/dc</link>
+  <pubDate>not a date</pubDate>
+  <dc:date>2006-01-02T15:04:05+02:00</dc:date>
+</item>
+</channel></rss>`
+
+func TestScrapeFeedReplacesAttachmentsAndTags(t *testing.T) {
+	body := `<rss version="2.0"><channel><title>Podcast</title>
+<item><title>Episode</title><link>https://example.com/ep</link><guid>ep</guid>
+<enclosure url="https://example.com/old.mp3" type="audio/mpeg" length="1"/>
+<category>Old</category></item>
+</channel></rss>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	scraper, store := newTestScraper()
+	feed := testFeed(srv.URL)
+	ctx := context.Background()
+
+	scraper.ScrapeFeed(ctx, feed)
+	// Only the episode's enclosure and category are replaced
+	body = strings.NewReplacer("old.mp3", "new.mp3", "Old", "New").Replace(body)
+	scraper.ScrapeFeed(ctx, feed)
+
+	post := store.postsByGUID(feed.ID)["ep"]
+	attachments := store.attachments[post.ID]
+	if len(attachments) != 1 || attachments[0].Url != "https://example.com/new.mp3" {
+		t.Errorf("attachments = %+v, want only new.mp3", attachments)
+	}
+	if tags := store.tags[post.ID]; len(tags) != 1 || tags[0] != "new" {
+		t.Errorf("tags = %v, want [new]", tags)
+	}
+}
+
+func TestScrapeFeedBackfillsOlderPosts(t *testing.T) {
+	body := `<rss version="2.0"><channel><title>Podcast</title>
+<item><title>Episode</title><link>https://example.com/ep</link><guid>ep</guid>
+<enclosure url="https://example.com/ep.mp3" type="audio/mpeg" length="1"/></item>
+<item><title>Legacy</title><link>https://example.com/legacy</link><guid>legacy</guid>
+<enclosure url="https://example.com/legacy.mp3" type="audio/mpeg" length="1"/><category>Go</category></item>
+</channel></rss>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	scraper, store := newTestScraper()
+	feed := testFeed(srv.URL)
+	ctx := context.Background()
+
+	// A post stored before attachments and tags were tracked has none
+	legacy := database.Post{
+		ID:         uuid.New(),
+		Title:      "Legacy",
+		Url:        "https://example.com/legacy",
+		FeedID:     feed.ID,
+		Guid:       "legacy",
+		DateSource: DateSourceFirstSeen,
+	}
+	store.posts[feed.ID.String()+"|"+legacy.Guid] = legacy
+
+	scraper.ScrapeFeed(ctx, feed)
+	if got := store.attachments[legacy.ID]; len(got) != 1 || got[0].Url != "https://example.com/legacy.mp3" {
+		t.Errorf("legacy post attachments = %+v, want legacy.mp3", got)
+	}
+	if got := store.tags[legacy.ID]; len(got) != 1 || got[0] != "go" {
+		t.Errorf("legacy post tags = %v, want [go]", got)
+	}
+
+	// Only the episode's enclosure is edited
+	body = strings.Replace(body, `length="1"`, `length="2"`, 1)
+	scraper.ScrapeFeed(ctx, feed)
+
+	post := store.postsByGUID(feed.ID)["ep"]
+	if got := store.attachments[post.ID]; len(got) != 1 || got[0].Length.Int64 != 2 {
+		t.Errorf("episode attachments = %+v, want the edited enclosure", got)
+	}
+}
+
+func TestScrapeFeedKeepsValidatorsUntilIngested(t *testing.T) {
+	const etag = `"v1"`
+	var conditional int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			atomic.AddInt32(&conditional, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(duplicatesFeed))
+	}))
+	defer srv.Close()
+	scraper, store := newTestScraper()
+	feed := testFeed(srv.URL)
+	ctx := context.Background()
+
+	// The database fails while the items are stored
+	store.upsertErr = errors.New("connection reset")
+	scraper.ScrapeFeed(ctx, feed)
+	if _, ok := store.validators[feed.ID]; ok {
+		t.Error("validators saved although no item was stored")
+	}
+
+	// The next fetch is not conditional, so the items are stored after all
+	store.upsertErr = nil
+	feed.Etag = store.validators[feed.ID].Etag
+	scraper.ScrapeFeed(ctx, feed)
+	if got := atomic.LoadInt32(&conditional); got != 0 {
+		t.Errorf("server answered %d conditional fetches with 304, want 0", got)
+	}
+	if got := len(store.postsByGUID(feed.ID)); got != 2 {
+		t.Fatalf("stored %d posts, want 2", got)
+	}
+
+	// Once every item is stored, the next fetch is conditional
+	feed.Etag = store.validators[feed.ID].Etag
+	scraper.ScrapeFeed(ctx, feed)
+	if got := atomic.LoadInt32(&conditional); got != 1 {
+		t.Errorf("server answered %d conditional fetches with 304, want 1", got)
+	}
+}
+
+func TestIngestFeedFinishesPostWhenCancelled(t *testing.T) {
+	feedData, err := ParseFeed([]byte(`<rss version="2.0"><channel><title>Podcast</title>
+<item><title>One</title><link>https://example.com/1</link>
+<enclosure url="https://example.com/1.mp3" type="audio/mpeg" length="1"/><category>Go</category></item>
+<item><title>Two</title><link>https://example.com/2</link></item>
+</channel></rss>`), "application/rss+xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scraper, store := newTestScraper()
+	feed := testFeed("https://example.com/feed")
+
+	// Shutting down right after the first post was upserted
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store.afterUpsert = cancel
+
+	inserted, _, err := scraper.IngestFeed(ctx, feed, feedData)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("IngestFeed() error = %v, want %v", err, context.Canceled)
+	}
+	if inserted != 1 {
+		t.Fatalf("inserted %d posts, want 1", inserted)
+	}
+	post := store.postsByGUID(feed.ID)["https://example.com/1"]
+	if got := len(store.attachments[post.ID]); got != 1 {
+		t.Errorf("stored %d attachments of the interrupted post, want 1", got)
+	}
+	if got := len(store.tags[post.ID]); got != 1 {
+		t.Errorf("stored %d tags of the interrupted post, want 1", got)
+	}
+}
+
+func TestDrainContext(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	drainCtx, cancel := drainContext(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	stop()
+	select {
+	case <-drainCtx.Done():
+		t.Fatal("drain context cancelled with its parent")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	select {
+	case <-drainCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("drain context not cancelled after its timeout")
+	}
+}
+
+func TestScrapeFeedBadDates(t *testing.T) {
+	scraper, store := newTestScraper()
+	feed := testFeed(serveFeed(t, badDatesFeed).URL)
+	ctx := context.Background()
+
+	before := time.Now().UTC()
+	scraper.ScrapeFeed(ctx, feed)
+	posts := store.postsByGUID(feed.ID)
+
+	tests := []struct {
+		guid           string
+		wantDateSource string
+		wantPublished  time.Time // zero when dated when first seen
+	}{
+		{guid: "https://example.com/garbage", wantDateSource: DateSourceFirstSeen},
+		{guid: "https://example.com/undated", wantDateSource: DateSourceFirstSeen},
+		{guid: "https://example.com/dc", wantDateSource: DateSourceDCDate, wantPublished: time.Date(2006, 1, 2, 13, 4, 5, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.guid, func(t *testing.T) {
+			post, ok := posts[tt.guid]
+			if !ok {
+				t.Fatal("item with a bad date was dropped")
+			}
+			if post.DateSource != tt.wantDateSource {
+				t.Errorf("DateSource = %q, want %q", post.DateSource, tt.wantDateSource)
+			}
+			published := post.PublishedAt.Time
+			if published.Location() != time.UTC {
+				t.Errorf("PublishedAt %v is not in UTC", published)
+			}
+			if tt.wantPublished.IsZero() {
+				if published.Before(before) {
+					t.Errorf("PublishedAt = %v, want the time it was first seen", published)
+				}
+			} else if !published.Equal(tt.wantPublished) {
+				t.Errorf("PublishedAt = %v, want %v", published, tt.wantPublished)
+			}
+		})
+	}
+
+	// Posts dated when first seen keep their date on later fetches
+	scraper.ScrapeFeed(ctx, feed)
+	for guid, post := range store.postsByGUID(feed.ID) {
+		if !post.PublishedAt.Time.Equal(posts[guid].PublishedAt.Time) {
+			t.Errorf("%s: PublishedAt moved from %v to %v", guid, posts[guid].PublishedAt.Time, post.PublishedAt.Time)
+		}
+	}
+}
+
+func TestScrapeFeedAdoptsPostsStoredByLink(t *testing.T) {
+	scraper, store := newTestScraper()
+	feed := testFeed(serveFeed(t, duplicatesFeed).URL)
+	feed.AdoptPostGuids = true
+	ctx := context.Background()
+
+	// A post stored before GUIDs were tracked has its link as GUID
+	published := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	legacy := database.Post{
+		ID:          uuid.New(),
+		Title:       "First again",
+		Url:         "https://example.com/1",
+		PublishedAt: sql.NullTime{Time: published, Valid: true},
+		FeedID:      feed.ID,
+		Guid:        "https://example.com/1",
+		DateSource:  DateSourcePubDate,
+	}
+	store.posts[feed.ID.String()+"|"+legacy.Guid] = legacy
+
+	scraper.ScrapeFeed(ctx, feed)
+
+	posts := store.postsByGUID(feed.ID)
+	if len(posts) != 2 {
+		t.Fatalf("stored %d posts, want 2", len(posts))
+	}
+	if got := posts["post-1"].ID; got != legacy.ID {
+		t.Errorf("post-1 stored as %v, want the legacy post %v", got, legacy.ID)
+	}
+	if _, ok := posts[legacy.Url]; ok {
+		t.Error("legacy post still keyed by its link")
+	}
+
+	// Once every item was stored, the feed stops adopting posts
+	if !store.adopted[feed.ID] {
+		t.Fatal("post GUID adoption not finished after a complete fetch")
+	}
+	feed.AdoptPostGuids = false
+	adoptions := store.adoptions
+	scraper.ScrapeFeed(ctx, feed)
+	if store.adoptions != adoptions {
+		t.Errorf("tried to adopt %d posts after adoption finished", store.adoptions-adoptions)
+	}
+}
+
+func TestKeepLease(t *testing.T) {
+	scraper, store := newTestScraper()
+	feed := testFeed("https://example.com/feed")
+
+	stop := scraper.keepLease(feed, 10*time.Millisecond)
+	time.Sleep(55 * time.Millisecond)
+	stop()
+
+	store.mu.Lock()
+	extended := store.extensions
+	store.mu.Unlock()
+	if extended < 2 {
+		t.Errorf("lease extended %d times, want at least 2", extended)
+	}
+
+	// No extension happens once stopped
+	time.Sleep(30 * time.Millisecond)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.extensions != extended {
+		t.Errorf("lease extended %d times after stopping", store.extensions-extended)
+	}
+}
+
+func TestSanitizeStoredPosts(t *testing.T) {
+	scraper, store := newTestScraper()
+	feedID := uuid.New()
+	for i := 0; i < sanitizeBatchSize+1; i++ {
+		guid := fmt.Sprintf("legacy-%d", i)
+		store.posts[feedID.String()+"|"+guid] = database.Post{
+			ID:          uuid.New(),
+			Url:         "https://example.com/" + guid,
+			FeedID:      feedID,
+			Guid:        guid,
+			Description: sql.NullString{String: `<p onclick="steal()">Hi<script>alert(1)</script></p>`, Valid: true},
+			Content:     sql.NullString{String: `<a href="javascript:alert(1)">link</a>`, Valid: true},
+		}
+	}
+
+	scraper.SanitizeStoredPosts(context.Background())
+
+	for guid, post := range store.postsByGUID(feedID) {
+		if !post.Sanitized {
+			t.Fatalf("%s not sanitized", guid)
+		}
+		if post.Description.String != "<p>Hi</p>" || post.Preview.String != "Hi" {
+			t.Errorf("%s: description %q, preview %q", guid, post.Description.String, post.Preview.String)
+		}
+		if strings.Contains(post.Content.String, "javascript") {
+			t.Errorf("%s: content %q", guid, post.Content.String)
+		}
+	}
+}
+
+func TestScrapeFeedHTTPErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantFailures int32
+		wantGone     bool
+	}{
+		{name: "server error", status: http.StatusInternalServerError, wantFailures: 1},
+		{name: "not found", status: http.StatusNotFound, wantFailures: 1},
+		{name: "rate limited", status: http.StatusTooManyRequests, wantFailures: 1},
+		{name: "gone", status: http.StatusGone, wantGone: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scraper, store := newTestScraper()
+			feed := testFeed(serveStatus(t, tt.status).URL)
+
+			scraper.ScrapeFeed(context.Background(), feed)
+
+			if got := store.failures[feed.ID]; got != tt.wantFailures {
+				t.Errorf("consecutive failures = %d, want %d", got, tt.wantFailures)
+			}
+			if store.gone[feed.ID] != tt.wantGone {
+				t.Errorf("gone = %v, want %v", store.gone[feed.ID], tt.wantGone)
+			}
+			if store.successes != 0 {
+				t.Errorf("recorded %d successes for a failed fetch", store.successes)
+			}
+			if got := len(store.postsByGUID(feed.ID)); got != 0 {
+				t.Errorf("stored %d posts for a failed fetch", got)
+			}
+			fetch := store.lastFetch(t)
+			if !fetch.StatusCode.Valid || fetch.StatusCode.Int32 != int32(tt.status) || !fetch.Error.Valid {
+				t.Errorf("fetch recorded status %v and error %v, want %d and an error", fetch.StatusCode, fetch.Error, tt.status)
+			}
+		})
+	}
+}
+
+func TestScrapeFeedDisablesAfterMaxFailures(t *testing.T) {
+	scraper, store := newTestScraper()
+	feed := testFeed(serveStatus(t, http.StatusServiceUnavailable).URL)
+	ctx := context.Background()
+
+	for i := 0; i < scraper.MaxFailures; i++ {
+		if store.disabled[feed.ID] {
+			t.Fatalf("feed disabled after %d failures, want %d", i, scraper.MaxFailures)
+		}
+		scraper.ScrapeFeed(ctx, feed)
+	}
+	if !store.disabled[feed.ID] {
+		t.Errorf("feed not disabled after %d failures", scraper.MaxFailures)
+	}
+
+	// Failures before the last one back the feed off instead
+	if len(store.schedules) != scraper.MaxFailures-1 {
+		t.Fatalf("scheduled %d fetches, want %d", len(store.schedules), scraper.MaxFailures-1)
+	}
+	if first, second := store.schedules[0].NextFetchAt.Time, store.schedules[1].NextFetchAt.Time; !second.After(first) {
+		t.Errorf("backoff did not grow: %v then %v", first, second)
+	}
+}
+
+// failingFetcher fails every fetch with err.
+type failingFetcher struct {
+	err error
+}
+
+func (f failingFetcher) FetchFeed(ctx context.Context, feedURL, etag, lastModified string, creds *models.FeedCredentials) (*FetchResult, error) {
+	return nil, f.err
+}
+
+func TestScrapeFeedHostBusy(t *testing.T) {
+	scraper, store := newTestScraper()
+	scraper.Fetcher = failingFetcher{err: fmt.Errorf("fetching feed: %w", ErrHostBusy)}
+	feed := testFeed("https://example.com/feed")
+
+	before := time.Now()
+	scraper.ScrapeFeed(context.Background(), feed)
+
+	if store.failures[feed.ID] != 0 {
+		t.Errorf("consecutive failures = %d, want 0", store.failures[feed.ID])
+	}
+	if len(store.schedules) != 1 {
+		t.Fatalf("scheduled %d fetches, want 1", len(store.schedules))
+	}
+	if next := store.schedules[0].NextFetchAt.Time; next.Before(before.Add(HostBusyRetryDelay)) {
+		t.Errorf("rescheduled for %v, want %v later", next, HostBusyRetryDelay)
+	}
+}
+
+func TestScrapeFeedConnectionError(t *testing.T) {
+	scraper, store := newTestScraper()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	feed := testFeed(srv.URL)
+	srv.Close()
+
+	scraper.ScrapeFeed(context.Background(), feed)
+
+	if store.failures[feed.ID] != 1 {
+		t.Errorf("consecutive failures = %d, want 1", store.failures[feed.ID])
+	}
+	if fetch := store.lastFetch(t); fetch.StatusCode.Valid || !fetch.Error.Valid {
+		t.Errorf("fetch recorded status %v and error %v, want no status and an error", fetch.StatusCode, fetch.Error)
+	}
+}
@@ -0,0 +1,116 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrInvalidFeedURL is returned for feed URLs that are not absolute http(s) URLs.
+	ErrInvalidFeedURL = errors.New("feed URL must be an absolute http or https URL")
+	// ErrForbiddenAddress is returned when a URL resolves to a private, loopback or otherwise internal address.
+	ErrForbiddenAddress = errors.New("address is not publicly routable")
+)
+
+// forbiddenNetworks are internal ranges not covered by the net.IP classification methods.
+var forbiddenNetworks = mustParseCIDRs(
+	"0.0.0.0/8",      // "this" network
+	"100.64.0.0/10",  // carrier-grade NAT
+	"192.0.0.0/24",   // IETF protocol assignments
+	"198.18.0.0/15",  // benchmarking
+	"240.0.0.0/4",    // reserved, including broadcast
+	"64:ff9b::/96",   // NAT64, embeds IPv4 addresses
+	"64:ff9b:1::/48", // local-use NAT64
+	"2002::/16",      // 6to4, embeds IPv4 addresses
+	"2001::/32",      // Teredo, embeds IPv4 addresses
+	"fec0::/10",      // deprecated site-local
+	"100::/64",       // discard-only
+)
+
+// ValidateFeedURL checks that a user-supplied feed URL is an absolute http(s) URL
+// without credentials whose host, if given as an IP address, is publicly routable.
+// Host names are checked again when connecting, after they have been resolved.
+func ValidateFeedURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", ErrInvalidFeedURL
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", ErrInvalidFeedURL
+	}
+	if u.Hostname() == "" || u.User != nil {
+		return "", ErrInvalidFeedURL
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && IsForbiddenIP(ip) {
+		return "", ErrForbiddenAddress
+	}
+	return u.String(), nil
+}
+
+// IsForbiddenIP reports whether ip is a private, loopback, link-local or otherwise
+// internal address that feeds must not be fetched from.
+func IsForbiddenIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range forbiddenNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSafeTransport returns an HTTP transport whose connections may only reach
+// publicly routable addresses. The check runs on the resolved address at connect
+// time, so DNS names pointing at internal hosts and redirects to them are refused.
+// Proxies from the environment are ignored, since they would bypass the check.
+func NewSafeTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   safeControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// safeControl refuses connections to forbidden addresses.
+func safeControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || IsForbiddenIP(ip) {
+		return fmt.Errorf("connecting to %s: %w", host, ErrForbiddenAddress)
+	}
+	return nil
+}
+
+// mustParseCIDRs parses a list of CIDR ranges, panicking on invalid input.
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
@@ -0,0 +1,354 @@
+package helper
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qmranik/rss-aggregator-backend/internal/database"
+	"github.com/qmranik/rss-aggregator-backend/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// States of a WebSub subscription.
+const (
+	WebSubPending = "pending" // WebSubPending is awaiting verification of intent by the hub.
+	WebSubActive  = "active"  // WebSubActive has been verified and receives content until its lease expires.
+	WebSubDenied  = "denied"  // WebSubDenied was rejected by the hub.
+)
+
+const (
+	// DefaultWebSubLease is the lease requested from hubs and assumed when a hub does not state one.
+	DefaultWebSubLease = 10 * 24 * time.Hour
+	// MinWebSubLease and MaxWebSubLease bound the lease granted by a hub.
+	MinWebSubLease = time.Hour
+	MaxWebSubLease = 365 * 24 * time.Hour
+	// WebSubVerifyWindow is how long a hub may take to verify a subscription request.
+	WebSubVerifyWindow = 24 * time.Hour
+	// MaxPushBodySize is the largest content distribution request accepted from a hub.
+	MaxPushBodySize = 5 << 20
+
+	webSubRenewMargin   = 24 * time.Hour // renew leases this long before they expire
+	webSubRetryInterval = time.Hour      // retry unverified subscriptions after this long
+	webSubDeniedRetry   = 7 * 24 * time.Hour
+	webSubRenewBatch    = 50
+)
+
+// ErrInsecureHub is returned when subscribing to a hub that is not reached over https,
+// which would expose the subscription's secret.
+var ErrInsecureHub = errors.New("WebSub hub does not use https")
+
+// WebSubStore persists WebSub subscriptions. It is satisfied by *database.Queries.
+type WebSubStore interface {
+	GetWebSubSubscription(ctx context.Context, feedID uuid.UUID) (database.WebsubSubscription, error)
+	UpsertWebSubSubscription(ctx context.Context, arg database.UpsertWebSubSubscriptionParams) error
+	GetWebSubSubscriptionsToRenew(ctx context.Context, arg database.GetWebSubSubscriptionsToRenewParams) ([]database.WebsubSubscription, error)
+}
+
+// WebSubSubscriber subscribes to the WebSub hubs advertised by feeds so that new
+// content is pushed to the callback route instead of being polled.
+type WebSubSubscriber struct {
+	Store       WebSubStore  // Store persists subscriptions.
+	Client      *http.Client // Client sends subscription requests to hubs.
+	CallbackURL string       // CallbackURL is the public URL of the callback route, the feed ID is appended.
+	UserAgent   string       // UserAgent identifies the subscriber to hubs, DefaultUserAgent if empty.
+}
+
+// Callback returns the callback URL of a feed's subscription.
+func (w *WebSubSubscriber) Callback(feedID uuid.UUID) string {
+	return strings.TrimRight(w.CallbackURL, "/") + "/" + feedID.String()
+}
+
+// Discover subscribes a feed to the first https hub it advertises, renewing or replacing
+// an existing subscription when needed. It reports whether content of the feed is
+// currently being pushed by the hub. Feeds without an https hub are polled.
+func (w *WebSubSubscriber) Discover(ctx context.Context, feed database.Feed, hubs []string, topic string) bool {
+	hub := secureHub(hubs)
+	if hub == "" || topic == "" {
+		return false
+	}
+
+	sub, err := w.Store.GetWebSubSubscription(ctx, feed.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.WithFields(log.Fields{
+			"feedID": feed.ID,
+			"error":  err,
+		}).Error("Couldn't get WebSub subscription")
+		return false
+	}
+	found := err == nil
+	now := time.Now().UTC()
+	current := found && sub.Hub == hub && sub.Topic == topic
+	if current && !needsSubscribe(sub, now) {
+		return isPushed(sub, now)
+	}
+
+	// Keep the secret while renewing, the hub may still sign with it
+	secret := sub.Secret
+	if !current {
+		secret, err = newWebSubSecret()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"feedID": feed.ID,
+				"error":  err,
+			}).Error("Couldn't generate WebSub secret")
+			return false
+		}
+	}
+	if err := w.subscribe(ctx, feed.ID, hub, topic, secret); err != nil {
+		log.WithFields(log.Fields{
+			"feedID": feed.ID,
+			"hub":    hub,
+			"topic":  topic,
+			"error":  err,
+		}).Warn("Couldn't subscribe to WebSub hub")
+	}
+	return current && isPushed(sub, now)
+}
+
+// RenewLeases re-subscribes active subscriptions whose lease is about to expire.
+func (w *WebSubSubscriber) RenewLeases(ctx context.Context) {
+	now := time.Now().UTC()
+	subs, err := w.Store.GetWebSubSubscriptionsToRenew(ctx, database.GetWebSubSubscriptionsToRenewParams{
+		LeaseExpiresAt: sql.NullTime{Time: now.Add(webSubRenewMargin), Valid: true},
+		UpdatedAt:      now.Add(-webSubRetryInterval),
+		Limit:          webSubRenewBatch,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("Couldn't get WebSub subscriptions to renew")
+		return
+	}
+
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := w.subscribe(ctx, sub.FeedID, sub.Hub, sub.Topic, sub.Secret); err != nil {
+			log.WithFields(log.Fields{
+				"feedID": sub.FeedID,
+				"hub":    sub.Hub,
+				"error":  err,
+			}).Warn("Couldn't renew WebSub subscription")
+		}
+	}
+}
+
+// subscribe records a subscription and sends the subscription request to the hub,
+// which then verifies our intent through the callback route. The hub must use https.
+func (w *WebSubSubscriber) subscribe(ctx context.Context, feedID uuid.UUID, hub, topic, secret string) error {
+	if secureHub([]string{hub}) == "" {
+		return ErrInsecureHub
+	}
+
+	// Store the subscription first, the hub may verify it before answering
+	now := time.Now().UTC()
+	err := w.Store.UpsertWebSubSubscription(ctx, database.UpsertWebSubSubscriptionParams{
+		FeedID:    feedID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Hub:       hub,
+		Topic:     topic,
+		Secret:    secret,
+	})
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topic},
+		"hub.callback":      {w.Callback(feedID)},
+		"hub.secret":        {secret},
+		"hub.lease_seconds": {strconv.Itoa(int(DefaultWebSubLease / time.Second))},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	userAgent := w.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("hub answered subscription request with %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
+
+// secureHub returns the first of hubs reached over https, the hub.secret sent when
+// subscribing must not travel in the clear. It returns "" when there is none.
+func secureHub(hubs []string) string {
+	for _, hub := range hubs {
+		hub = strings.TrimSpace(hub)
+		u, err := url.Parse(hub)
+		if err == nil && strings.EqualFold(u.Scheme, "https") && u.Host != "" {
+			return hub
+		}
+	}
+	return ""
+}
+
+// needsSubscribe reports whether a subscription to the same hub and topic should
+// be (re)sent, without retrying unanswered requests too eagerly.
+func needsSubscribe(sub database.WebsubSubscription, now time.Time) bool {
+	switch sub.State {
+	case WebSubActive:
+		if sub.LeaseExpiresAt.Valid && sub.LeaseExpiresAt.Time.After(now.Add(webSubRenewMargin)) {
+			return false
+		}
+		return sub.UpdatedAt.Before(now.Add(-webSubRetryInterval))
+	case WebSubDenied:
+		return sub.UpdatedAt.Before(now.Add(-webSubDeniedRetry))
+	default:
+		return sub.UpdatedAt.Before(now.Add(-webSubRetryInterval))
+	}
+}
+
+// isPushed reports whether a subscription is verified and its lease has not expired.
+func isPushed(sub database.WebsubSubscription, now time.Time) bool {
+	return sub.State == WebSubActive && sub.LeaseExpiresAt.Valid && sub.LeaseExpiresAt.Time.After(now)
+}
+
+// AwaitsVerification reports whether a subscription request was sent for sub and has not
+// been verified or denied yet. Hubs may only verify outstanding requests, so nobody else can
+// activate a subscription or change its lease. A new subscription is pending until it is
+// verified, a renewed one stays active while its renewal is outstanding.
+func AwaitsVerification(sub database.WebsubSubscription, now time.Time) bool {
+	if !sub.RequestedAt.Valid || sub.RequestedAt.Time.Before(now.Add(-WebSubVerifyWindow)) {
+		return false
+	}
+	return sub.State == WebSubPending || sub.State == WebSubActive
+}
+
+// WebSubLease returns the lease stated by the hub.lease_seconds parameter of a verification,
+// DefaultWebSubLease when it is missing or invalid, clamped between MinWebSubLease and MaxWebSubLease.
+func WebSubLease(leaseSeconds string) time.Duration {
+	seconds, err := strconv.ParseInt(leaseSeconds, 10, 64)
+	if err != nil || seconds <= 0 {
+		return DefaultWebSubLease
+	}
+	// Clamp the seconds before converting them, large values would overflow a Duration
+	if seconds < int64(MinWebSubLease/time.Second) {
+		return MinWebSubLease
+	}
+	if seconds > int64(MaxWebSubLease/time.Second) {
+		return MaxWebSubLease
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// newWebSubSecret generates the secret a hub uses to sign distributed content.
+func newWebSubSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// VerifyWebSubSignature checks the X-Hub-Signature header of a content distribution
+// request, in the form "method=hexdigest", against the HMAC of body keyed with secret.
+func VerifyWebSubSignature(secret string, body []byte, header string) bool {
+	method, signature, ok := strings.Cut(strings.TrimSpace(header), "=")
+	if !ok {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch strings.ToLower(method) {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// webSubLinks returns the hubs and topic of a fetched feed. Link headers take
+// precedence over links in the document and the topic defaults to the fetched URL.
+func webSubLinks(header http.Header, fetchedURL string, channel *models.RSSChannel) (hubs []string, topic string) {
+	for _, value := range header.Values("Link") {
+		for _, link := range parseLinkHeader(value) {
+			switch {
+			case hasToken(link.Rel, "hub"):
+				hubs = append(hubs, link.Href)
+			case hasToken(link.Rel, "self") && topic == "":
+				topic = link.Href
+			}
+		}
+	}
+
+	if len(hubs) == 0 {
+		var self string
+		hubs, self = channel.WebSubLinks()
+		if topic == "" {
+			topic = self
+		}
+	}
+	if topic == "" {
+		topic = fetchedURL
+	}
+	return hubs, topic
+}
+
+// parseLinkHeader parses the links of an RFC 8288 Link header value.
+func parseLinkHeader(value string) []models.AtomLink {
+	var links []models.AtomLink
+	for _, part := range strings.Split(value, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		target = strings.TrimSpace(target)
+		if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+
+		link := models.AtomLink{Href: strings.Trim(target, "<>")}
+		for _, param := range strings.Split(params, ";") {
+			name, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(strings.TrimSpace(name), "rel") {
+				link.Rel = strings.ToLower(strings.Trim(strings.TrimSpace(val), `"`))
+			}
+		}
+		links = append(links, link)
+	}
+	return links
+}
@@ -0,0 +1,135 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qmranik/rss-aggregator-backend/internal/database"
+)
+
+func TestWebSubLease(t *testing.T) {
+	tests := []struct {
+		leaseSeconds string
+		want         time.Duration
+	}{
+		{"", DefaultWebSubLease},
+		{"soon", DefaultWebSubLease},
+		{"0", DefaultWebSubLease},
+		{"-86400", DefaultWebSubLease},
+		{"86400", 24 * time.Hour},
+		{"60", MinWebSubLease},
+		{"99999999999", MaxWebSubLease},
+		{strconv.FormatInt(1<<62, 10), MaxWebSubLease},
+		{"99999999999999999999999", DefaultWebSubLease},
+	}
+
+	for _, tt := range tests {
+		if got := WebSubLease(tt.leaseSeconds); got != tt.want {
+			t.Errorf("WebSubLease(%q) = %v, want %v", tt.leaseSeconds, got, tt.want)
+		}
+	}
+}
+
+func TestAwaitsVerification(t *testing.T) {
+	now := time.Now().UTC()
+	requested := sql.NullTime{Time: now.Add(-time.Minute), Valid: true}
+
+	tests := []struct {
+		name string
+		sub  database.WebsubSubscription
+		want bool
+	}{
+		{name: "pending request", sub: database.WebsubSubscription{State: WebSubPending, RequestedAt: requested}, want: true},
+		{name: "renewal of an active subscription", sub: database.WebsubSubscription{State: WebSubActive, RequestedAt: requested}, want: true},
+		{name: "verified subscription", sub: database.WebsubSubscription{State: WebSubActive}},
+		{name: "pending without a request", sub: database.WebsubSubscription{State: WebSubPending}},
+		{name: "denied subscription", sub: database.WebsubSubscription{State: WebSubDenied, RequestedAt: requested}},
+		{
+			name: "request too old",
+			sub: database.WebsubSubscription{
+				State:       WebSubPending,
+				RequestedAt: sql.NullTime{Time: now.Add(-WebSubVerifyWindow - time.Minute), Valid: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AwaitsVerification(tt.sub, now); got != tt.want {
+				t.Errorf("AwaitsVerification() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// memWebSubStore is an in-memory WebSubStore recording the subscriptions stored.
+type memWebSubStore struct {
+	mu   sync.Mutex
+	subs map[uuid.UUID]database.UpsertWebSubSubscriptionParams
+}
+
+func (m *memWebSubStore) GetWebSubSubscription(ctx context.Context, feedID uuid.UUID) (database.WebsubSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.subs[feedID]
+	if !ok {
+		return database.WebsubSubscription{}, sql.ErrNoRows
+	}
+	return database.WebsubSubscription{FeedID: sub.FeedID, Hub: sub.Hub, Topic: sub.Topic, Secret: sub.Secret, State: WebSubPending}, nil
+}
+
+func (m *memWebSubStore) UpsertWebSubSubscription(ctx context.Context, arg database.UpsertWebSubSubscriptionParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subs[arg.FeedID] = arg
+	return nil
+}
+
+func (m *memWebSubStore) GetWebSubSubscriptionsToRenew(ctx context.Context, arg database.GetWebSubSubscriptionsToRenewParams) ([]database.WebsubSubscription, error) {
+	return nil, nil
+}
+
+func TestWebSubDiscoverSecureHubsOnly(t *testing.T) {
+	var secrets []string
+	var mu sync.Mutex
+	hub := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		secrets = append(secrets, r.FormValue("hub.secret"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+	insecure := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("subscription request sent to the http hub with secret %q", r.FormValue("hub.secret"))
+	}))
+	defer insecure.Close()
+
+	store := &memWebSubStore{subs: make(map[uuid.UUID]database.UpsertWebSubSubscriptionParams)}
+	subscriber := &WebSubSubscriber{Store: store, Client: hub.Client(), CallbackURL: "https://example.com/v1/websub"}
+	ctx := context.Background()
+
+	// A feed advertising only an http hub is not subscribed
+	feed := database.Feed{ID: uuid.New()}
+	subscriber.Discover(ctx, feed, []string{insecure.URL}, "https://example.com/feed")
+	if _, ok := store.subs[feed.ID]; ok {
+		t.Error("subscribed to an http hub")
+	}
+
+	// The first https hub is used instead
+	subscriber.Discover(ctx, feed, []string{insecure.URL, hub.URL}, "https://example.com/feed")
+	if got := store.subs[feed.ID].Hub; got != hub.URL {
+		t.Errorf("subscribed to %q, want %q", got, hub.URL)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(secrets) != 1 || secrets[0] == "" {
+		t.Errorf("https hub received secrets %q, want one", secrets)
+	}
+}
@@ -7,24 +7,106 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET lease_owner = $1::text,
+lease_expires_at = NOW() + $2::int * INTERVAL '1 second',
+last_fetched_at = NOW(),
+updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE disabled_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+    AND (lease_expires_at IS NULL OR lease_expires_at <= NOW())
+    ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, poll_interval_seconds, consecutive_failures, last_error, last_success_at, disabled_at, blocked_by_robots, fetch_full_content, gone_at, lease_owner, lease_expires_at, title, description, site_url, language, image_url, icon_url, icon_checked_at, is_private, credentials, adopt_post_guids
+`
+
+type ClaimFeedsToFetchParams struct {
+	WorkerID     string
+	LeaseSeconds int32
+	BatchSize    int32
+}
+
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.WorkerID, arg.LeaseSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextFetchAt,
+			&i.PollIntervalSeconds,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.BlockedByRobots,
+			&i.FetchFullContent,
+			&i.GoneAt,
+			&i.LeaseOwner,
+			&i.LeaseExpiresAt,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.IconUrl,
+			&i.IconCheckedAt,
+			&i.IsPrivate,
+			&i.Credentials,
+			&i.AdoptPostGuids,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, fetch_full_content, is_private, credentials)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, poll_interval_seconds, consecutive_failures, last_error, last_success_at, disabled_at, blocked_by_robots, fetch_full_content, gone_at, lease_owner, lease_expires_at, title, description, site_url, language, image_url, icon_url, icon_checked_at, is_private, credentials, adopt_post_guids
 `
 
 type CreateFeedParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Name             string
+	Url              string
+	UserID           uuid.UUID
+	FetchFullContent bool
+	IsPrivate        bool
+	Credentials      []byte
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -35,7 +117,138 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.Name,
 		arg.Url,
 		arg.UserID,
+		arg.FetchFullContent,
+		arg.IsPrivate,
+		arg.Credentials,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.PollIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.BlockedByRobots,
+		&i.FetchFullContent,
+		&i.GoneAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.IconUrl,
+		&i.IconCheckedAt,
+		&i.IsPrivate,
+		&i.Credentials,
+		&i.AdoptPostGuids,
+	)
+	return i, err
+}
+
+const disableFeed = `-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = NOW(),
+updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DisableFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, disableFeed, id)
+	return err
+}
+
+const extendFeedLease = `-- name: ExtendFeedLease :exec
+UPDATE feeds
+SET lease_expires_at = NOW() + $1::int * INTERVAL '1 second'
+WHERE id = $2
+AND lease_owner = $3::text
+`
+
+type ExtendFeedLeaseParams struct {
+	LeaseSeconds int32
+	ID           uuid.UUID
+	WorkerID     string
+}
+
+func (q *Queries) ExtendFeedLease(ctx context.Context, arg ExtendFeedLeaseParams) error {
+	_, err := q.db.ExecContext(ctx, extendFeedLease, arg.LeaseSeconds, arg.ID, arg.WorkerID)
+	return err
+}
+
+const finishPostGUIDAdoption = `-- name: FinishPostGUIDAdoption :exec
+UPDATE feeds
+SET adopt_post_guids = false
+WHERE id = $1
+`
+
+func (q *Queries) FinishPostGUIDAdoption(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, finishPostGUIDAdoption, id)
+	return err
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, poll_interval_seconds, consecutive_failures, last_error, last_success_at, disabled_at, blocked_by_robots, fetch_full_content, gone_at, lease_owner, lease_expires_at, title, description, site_url, language, image_url, icon_url, icon_checked_at, is_private, credentials, adopt_post_guids FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.PollIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.BlockedByRobots,
+		&i.FetchFullContent,
+		&i.GoneAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.IconUrl,
+		&i.IconCheckedAt,
+		&i.IsPrivate,
+		&i.Credentials,
+		&i.AdoptPostGuids,
 	)
+	return i, err
+}
+
+const getFeedByURL = `-- name: GetFeedByURL :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, poll_interval_seconds, consecutive_failures, last_error, last_success_at, disabled_at, blocked_by_robots, fetch_full_content, gone_at, lease_owner, lease_expires_at, title, description, site_url, language, image_url, icon_url, icon_checked_at, is_private, credentials, adopt_post_guids FROM feeds
+WHERE url = $1
+AND NOT is_private
+`
+
+func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByURL, url)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -45,12 +258,36 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.PollIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.BlockedByRobots,
+		&i.FetchFullContent,
+		&i.GoneAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.IconUrl,
+		&i.IconCheckedAt,
+		&i.IsPrivate,
+		&i.Credentials,
+		&i.AdoptPostGuids,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, poll_interval_seconds, consecutive_failures, last_error, last_success_at, disabled_at, blocked_by_robots, fetch_full_content, gone_at, lease_owner, lease_expires_at, title, description, site_url, language, image_url, icon_url, icon_checked_at, is_private, credentials, adopt_post_guids FROM feeds
+WHERE NOT is_private
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -70,6 +307,29 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextFetchAt,
+			&i.PollIntervalSeconds,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.BlockedByRobots,
+			&i.FetchFullContent,
+			&i.GoneAt,
+			&i.LeaseOwner,
+			&i.LeaseExpiresAt,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.IconUrl,
+			&i.IconCheckedAt,
+			&i.IsPrivate,
+			&i.Credentials,
+			&i.AdoptPostGuids,
 		); err != nil {
 			return nil, err
 		}
@@ -84,53 +344,86 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 	return items, nil
 }
 
-const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT $1
+const markFeedBlockedByRobots = `-- name: MarkFeedBlockedByRobots :exec
+UPDATE feeds
+SET blocked_by_robots = TRUE,
+last_error = $2,
+updated_at = NOW()
+WHERE id = $1
 `
 
-func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed, error) {
-	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, limit)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Feed
-	for rows.Next() {
-		var i Feed
-		if err := rows.Scan(
-			&i.ID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.Name,
-			&i.Url,
-			&i.UserID,
-			&i.LastFetchedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type MarkFeedBlockedByRobotsParams struct {
+	ID        uuid.UUID
+	LastError sql.NullString
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :one
+func (q *Queries) MarkFeedBlockedByRobots(ctx context.Context, arg MarkFeedBlockedByRobotsParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedBlockedByRobots, arg.ID, arg.LastError)
+	return err
+}
+
+const markFeedGone = `-- name: MarkFeedGone :exec
 UPDATE feeds
-SET last_fetched_at = NOW(),
+SET gone_at = NOW(),
+disabled_at = NOW(),
+last_error = $2,
 updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, markFeedFetched, id)
+type MarkFeedGoneParams struct {
+	ID        uuid.UUID
+	LastError sql.NullString
+}
+
+func (q *Queries) MarkFeedGone(ctx context.Context, arg MarkFeedGoneParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedGone, arg.ID, arg.LastError)
+	return err
+}
+
+const mergeFeeds = `-- name: MergeFeeds :exec
+WITH moved_follows AS (
+    UPDATE feed_follows
+    SET feed_id = $1,
+    updated_at = NOW()
+    WHERE feed_id = $2
+    AND user_id NOT IN (SELECT user_id FROM feed_follows WHERE feed_id = $1)
+), moved_posts AS (
+    UPDATE posts
+    SET feed_id = $1
+    WHERE feed_id = $2
+    AND guid NOT IN (SELECT guid FROM posts WHERE feed_id = $1)
+)
+DELETE FROM feeds
+WHERE id = $2
+`
+
+type MergeFeedsParams struct {
+	TargetID uuid.UUID
+	SourceID uuid.UUID
+}
+
+func (q *Queries) MergeFeeds(ctx context.Context, arg MergeFeedsParams) error {
+	_, err := q.db.ExecContext(ctx, mergeFeeds, arg.TargetID, arg.SourceID)
+	return err
+}
+
+const recordFeedFailure = `-- name: RecordFeedFailure :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+last_error = $2,
+updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, poll_interval_seconds, consecutive_failures, last_error, last_success_at, disabled_at, blocked_by_robots, fetch_full_content, gone_at, lease_owner, lease_expires_at, title, description, site_url, language, image_url, icon_url, icon_checked_at, is_private, credentials, adopt_post_guids
+`
+
+type RecordFeedFailureParams struct {
+	ID        uuid.UUID
+	LastError sql.NullString
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFailure, arg.ID, arg.LastError)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -140,6 +433,168 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, erro
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.PollIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.BlockedByRobots,
+		&i.FetchFullContent,
+		&i.GoneAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.IconUrl,
+		&i.IconCheckedAt,
+		&i.IsPrivate,
+		&i.Credentials,
+		&i.AdoptPostGuids,
 	)
 	return i, err
 }
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+last_error = NULL,
+last_success_at = NOW(),
+blocked_by_robots = FALSE,
+updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, id)
+	return err
+}
+
+const releaseFeedLease = `-- name: ReleaseFeedLease :exec
+UPDATE feeds
+SET lease_owner = NULL,
+lease_expires_at = NULL
+WHERE id = $1
+AND lease_owner = $2
+`
+
+type ReleaseFeedLeaseParams struct {
+	ID         uuid.UUID
+	LeaseOwner sql.NullString
+}
+
+func (q *Queries) ReleaseFeedLease(ctx context.Context, arg ReleaseFeedLeaseParams) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedLease, arg.ID, arg.LeaseOwner)
+	return err
+}
+
+const scheduleFeedFetch = `-- name: ScheduleFeedFetch :exec
+UPDATE feeds
+SET next_fetch_at = $2,
+poll_interval_seconds = $3,
+updated_at = NOW()
+WHERE id = $1
+`
+
+type ScheduleFeedFetchParams struct {
+	ID                  uuid.UUID
+	NextFetchAt         sql.NullTime
+	PollIntervalSeconds int32
+}
+
+func (q *Queries) ScheduleFeedFetch(ctx context.Context, arg ScheduleFeedFetchParams) error {
+	_, err := q.db.ExecContext(ctx, scheduleFeedFetch, arg.ID, arg.NextFetchAt, arg.PollIntervalSeconds)
+	return err
+}
+
+const updateFeedCacheHeaders = `-- name: UpdateFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $2,
+last_modified = $3,
+updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedCacheHeadersParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) UpdateFeedCacheHeaders(ctx context.Context, arg UpdateFeedCacheHeadersParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheHeaders, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const updateFeedIcon = `-- name: UpdateFeedIcon :exec
+UPDATE feeds
+SET icon_url = $2,
+icon_checked_at = $3,
+updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedIconParams struct {
+	ID            uuid.UUID
+	IconUrl       sql.NullString
+	IconCheckedAt sql.NullTime
+}
+
+func (q *Queries) UpdateFeedIcon(ctx context.Context, arg UpdateFeedIconParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedIcon, arg.ID, arg.IconUrl, arg.IconCheckedAt)
+	return err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2,
+description = $3,
+site_url = $4,
+language = $5,
+image_url = $6,
+updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID
+	Title       sql.NullString
+	Description sql.NullString
+	SiteUrl     sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.SiteUrl,
+		arg.Language,
+		arg.ImageUrl,
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2,
+updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedURLParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.ID, arg.Url)
+	return err
+}
@@ -12,13 +12,48 @@ import (
 )
 
 type Feed struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	NextFetchAt         sql.NullTime
+	PollIntervalSeconds int32
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	LastSuccessAt       sql.NullTime
+	DisabledAt          sql.NullTime
+	BlockedByRobots     bool
+	FetchFullContent    bool
+	GoneAt              sql.NullTime
+	LeaseOwner          sql.NullString
+	LeaseExpiresAt      sql.NullTime
+	Title               sql.NullString
+	Description         sql.NullString
+	SiteUrl             sql.NullString
+	Language            sql.NullString
+	ImageUrl            sql.NullString
+	IconUrl             sql.NullString
+	IconCheckedAt       sql.NullTime
+	IsPrivate           bool
+	Credentials         []byte
+	AdoptPostGuids      bool
+}
+
+type FeedFetch struct {
 	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	FeedID        uuid.UUID
+	FetchedAt     time.Time
+	StatusCode    sql.NullInt32
+	DurationMs    int32
+	Bytes         int64
+	ItemsSeen     int32
+	ItemsInserted int32
+	Error         sql.NullString
 }
 
 type FeedFollow struct {
@@ -41,14 +76,44 @@ type Payment struct {
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     sql.NullString
+	PublishedAt     sql.NullTime
+	FeedID          uuid.UUID
+	Guid            string
+	Content         sql.NullString
+	Preview         sql.NullString
+	Author          sql.NullString
+	CommentsUrl     sql.NullString
+	DateSource      string
+	Sanitized       bool
+	AttachmentsHash sql.NullString
+	TagsHash        sql.NullString
+}
+
+type PostAttachment struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	PostID          uuid.UUID
+	Kind            string
+	Url             string
+	MimeType        sql.NullString
+	Length          sql.NullInt64
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	Season          sql.NullInt32
+	Width           sql.NullInt32
+	Height          sql.NullInt32
+}
+
+type PostTag struct {
+	PostID uuid.UUID
+	TagID  uuid.UUID
 }
 
 type RefreshToken struct {
@@ -71,6 +136,12 @@ type Refund struct {
 	UpdatedAt      sql.NullTime
 }
 
+type Tag struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Name      string
+}
+
 type User struct {
 	ID           uuid.UUID
 	CreatedAt    time.Time
@@ -89,3 +160,15 @@ type UserSession struct {
 	ExpiresAt time.Time
 	IsValid   sql.NullBool
 }
+
+type WebsubSubscription struct {
+	FeedID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Hub            string
+	Topic          string
+	Secret         string
+	State          string
+	LeaseExpiresAt sql.NullTime
+	RequestedAt    sql.NullTime
+}
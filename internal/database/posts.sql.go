@@ -13,51 +13,33 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+const adoptPostGUID = `-- name: AdoptPostGUID :exec
+
+UPDATE posts
+SET guid = $3
+WHERE id = (
+    SELECT id FROM posts
+    WHERE feed_id = $1 AND url = $2 AND guid = url
+    ORDER BY created_at
+    LIMIT 1
+)
+AND NOT EXISTS (SELECT 1 FROM posts WHERE feed_id = $1 AND guid = $3)
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+type AdoptPostGUIDParams struct {
+	FeedID uuid.UUID
+	Url    string
+	Guid   string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-	)
-	var i Post
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Title,
-		&i.Url,
-		&i.Description,
-		&i.PublishedAt,
-		&i.FeedID,
-	)
-	return i, err
+func (q *Queries) AdoptPostGUID(ctx context.Context, arg AdoptPostGUIDParams) error {
+	_, err := q.db.ExecContext(ctx, adoptPostGUID, arg.FeedID, arg.Url, arg.Guid)
+	return err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
 
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id FROM posts
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content, posts.preview, posts.author, posts.comments_url, posts.date_source, posts.sanitized, posts.attachments_hash, posts.tags_hash FROM posts
 JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
 WHERE feed_follows.user_id = $1
 ORDER BY posts.published_at DESC
@@ -87,6 +69,64 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Guid,
+			&i.Content,
+			&i.Preview,
+			&i.Author,
+			&i.CommentsUrl,
+			&i.DateSource,
+			&i.Sanitized,
+			&i.AttachmentsHash,
+			&i.TagsHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnsanitizedPosts = `-- name: GetUnsanitizedPosts :many
+
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content, preview, author, comments_url, date_source, sanitized, attachments_hash, tags_hash FROM posts
+WHERE NOT sanitized
+ORDER BY id
+LIMIT $1
+`
+
+func (q *Queries) GetUnsanitizedPosts(ctx context.Context, limit int32) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getUnsanitizedPosts, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+			&i.Content,
+			&i.Preview,
+			&i.Author,
+			&i.CommentsUrl,
+			&i.DateSource,
+			&i.Sanitized,
+			&i.AttachmentsHash,
+			&i.TagsHash,
 		); err != nil {
 			return nil, err
 		}
@@ -100,3 +140,132 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const updatePostContent = `-- name: UpdatePostContent :exec
+
+UPDATE posts
+SET content = $2
+WHERE id = $1
+`
+
+type UpdatePostContentParams struct {
+	ID      uuid.UUID
+	Content sql.NullString
+}
+
+func (q *Queries) UpdatePostContent(ctx context.Context, arg UpdatePostContentParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostContent, arg.ID, arg.Content)
+	return err
+}
+
+const updatePostSanitized = `-- name: UpdatePostSanitized :exec
+
+UPDATE posts
+SET description = $2,
+preview = $3,
+content = $4,
+sanitized = true
+WHERE id = $1
+`
+
+type UpdatePostSanitizedParams struct {
+	ID          uuid.UUID
+	Description sql.NullString
+	Preview     sql.NullString
+	Content     sql.NullString
+}
+
+func (q *Queries) UpdatePostSanitized(ctx context.Context, arg UpdatePostSanitizedParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostSanitized,
+		arg.ID,
+		arg.Description,
+		arg.Preview,
+		arg.Content,
+	)
+	return err
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, guid, preview, author, comments_url, date_source, attachments_hash, tags_hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+url = EXCLUDED.url,
+description = EXCLUDED.description,
+published_at = CASE WHEN EXCLUDED.date_source = 'first_seen' THEN posts.published_at ELSE EXCLUDED.published_at END,
+date_source = CASE WHEN EXCLUDED.date_source = 'first_seen' THEN posts.date_source ELSE EXCLUDED.date_source END,
+preview = EXCLUDED.preview,
+author = EXCLUDED.author,
+comments_url = EXCLUDED.comments_url,
+attachments_hash = EXCLUDED.attachments_hash,
+tags_hash = EXCLUDED.tags_hash,
+updated_at = EXCLUDED.updated_at
+WHERE posts.title IS DISTINCT FROM EXCLUDED.title
+OR posts.url IS DISTINCT FROM EXCLUDED.url
+OR posts.description IS DISTINCT FROM EXCLUDED.description
+OR (EXCLUDED.date_source <> 'first_seen' AND posts.published_at IS DISTINCT FROM EXCLUDED.published_at)
+OR posts.author IS DISTINCT FROM EXCLUDED.author
+OR posts.comments_url IS DISTINCT FROM EXCLUDED.comments_url
+OR posts.attachments_hash IS DISTINCT FROM EXCLUDED.attachments_hash
+OR posts.tags_hash IS DISTINCT FROM EXCLUDED.tags_hash
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content, preview, author, comments_url, date_source, sanitized, attachments_hash, tags_hash
+`
+
+type UpsertPostParams struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     sql.NullString
+	PublishedAt     sql.NullTime
+	FeedID          uuid.UUID
+	Guid            string
+	Preview         sql.NullString
+	Author          sql.NullString
+	CommentsUrl     sql.NullString
+	DateSource      string
+	AttachmentsHash sql.NullString
+	TagsHash        sql.NullString
+}
+
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Guid,
+		arg.Preview,
+		arg.Author,
+		arg.CommentsUrl,
+		arg.DateSource,
+		arg.AttachmentsHash,
+		arg.TagsHash,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.Content,
+		&i.Preview,
+		&i.Author,
+		&i.CommentsUrl,
+		&i.DateSource,
+		&i.Sanitized,
+		&i.AttachmentsHash,
+		&i.TagsHash,
+	)
+	return i, err
+}
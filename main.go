@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -19,6 +25,13 @@ import (
 	_ "github.com/lib/pq"
 )
 
+// shutdownTimeout bounds how long the server and scraper may take to drain on exit.
+const shutdownTimeout = 30 * time.Second
+
+// scraperDrainTimeout bounds how long the feeds being scraped may take to finish on exit,
+// leaving the scraper time to release their leases before shutdownTimeout.
+const scraperDrainTimeout = 20 * time.Second
+
 func main() {
 	// Load environment variables from .env file
 	if err := godotenv.Load(".env"); err != nil {
@@ -41,8 +54,13 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer db.Close()
 	dbQueries := database.New(db)
 
+	// Stop the background work on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize Authenticator with environment variables and token TTLs
 	authenticator := &auth.Authenticator{
 		DB:              dbQueries,
@@ -52,10 +70,74 @@ func main() {
 		RefreshTokenTTL: 7 * 24 * time.Hour, // Refresh token TTL set to 7 days
 	}
 
+	// Shared HTTP fetcher for feed collection and discovery, limited to a few
+	// spaced-out concurrent requests per host
+	const fetchMaxPerHost = 2
+	const fetchMinHostDelay = time.Second
+	fetcher := helper.NewHTTPFetcher(
+		os.Getenv("USER_AGENT"),
+		helper.NewHostLimiter(fetchMaxPerHost, fetchMinHostDelay),
+	)
+
+	// Background scraper settings
+	const collectionConcurrency = 10
+	const collectionInterval = time.Minute
+
+	// Feeds failing this many times in a row are disabled (0 never disables)
+	collectionMaxFailures := 10
+	if maxFailures := os.Getenv("FEED_MAX_FAILURES"); maxFailures != "" {
+		collectionMaxFailures, err = strconv.Atoi(maxFailures)
+		if err != nil {
+			log.Fatal("FEED_MAX_FAILURES environment variable must be an integer")
+		}
+	}
+
+	// Identify this instance in feed leases, so replicas can share the scrape workload
+	workerID := os.Getenv("WORKER_ID")
+	if workerID == "" {
+		hostname, _ := os.Hostname()
+		workerID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	// Credentials of private feeds are encrypted with this key, without it only public feeds can be added
+	var credentials *helper.CredentialCipher
+	if key := os.Getenv("FEED_CREDENTIALS_KEY"); key != "" {
+		credentials, err = helper.NewCredentialCipher(key)
+		if err != nil {
+			log.Fatalf("FEED_CREDENTIALS_KEY environment variable is invalid: %v", err)
+		}
+	}
+
+	scraper := &helper.Scraper{
+		WorkerID:     workerID,
+		Store:        dbQueries,
+		Fetcher:      fetcher,
+		Articles:     fetcher,
+		Icons:        fetcher,
+		Concurrency:  collectionConcurrency,
+		Interval:     collectionInterval,
+		DrainTimeout: scraperDrainTimeout,
+		MaxFailures:  collectionMaxFailures,
+		Credentials:  credentials,
+	}
+
+	// Subscribe to WebSub hubs when the callback route is publicly reachable
+	if callbackURL := os.Getenv("WEBSUB_CALLBACK_URL"); callbackURL != "" {
+		scraper.WebSub = &helper.WebSubSubscriber{
+			Store:       dbQueries,
+			Client:      &http.Client{Timeout: 10 * time.Second, Transport: helper.NewSafeTransport()},
+			CallbackURL: callbackURL,
+			UserAgent:   os.Getenv("USER_AGENT"),
+		}
+	}
+
 	// Initialize ApiConfig for handling user and feed-related requests
 	apiCfg := handlers.ApiConfig{
-		DB:   dbQueries,
-		Auth: authenticator,
+		DB:          dbQueries,
+		Auth:        authenticator,
+		Fetcher:     fetcher,
+		Scraper:     scraper,
+		Credentials: credentials,
 	}
 
 	// Initialize UserHandler with Authenticator
@@ -99,12 +181,17 @@ func main() {
 	// Feed Routes
 	v1Router.Post("/feeds", authenticator.MiddlewareAuth(apiCfg.HandlerFeedCreate))
 	v1Router.Get("/feeds", apiCfg.HandlerGetFeeds)
+	v1Router.Get("/feeds/{feedID}/fetches", authenticator.MiddlewareAuth(apiCfg.HandlerFeedFetchesGet))
 
 	// Feed Follow Routes
 	v1Router.Get("/feed_follows", authenticator.MiddlewareAuth(apiCfg.HandlerFeedFollowsGet))
 	v1Router.Post("/feed_follows", authenticator.MiddlewareAuth(apiCfg.HandlerFeedFollowCreate))
 	v1Router.Delete("/feed_follows/{feedFollowID}", authenticator.MiddlewareAuth(apiCfg.HandlerFeedFollowDelete))
 
+	// WebSub Callback Routes
+	v1Router.Get("/websub/{feedID}", apiCfg.HandlerWebSubVerify)
+	v1Router.Post("/websub/{feedID}", apiCfg.HandlerWebSubNotify)
+
 	// Post Routes
 	v1Router.Get("/posts", authenticator.MiddlewareAuth(apiCfg.HandlerPostsGet))
 
@@ -126,10 +213,33 @@ func main() {
 	}
 
 	// Start background tasks for scraping
-	const collectionConcurrency = 10
-	const collectionInterval = time.Minute
-	go helper.StartScraping(dbQueries, collectionConcurrency, collectionInterval)
+	scraperDone := make(chan struct{})
+	go func() {
+		defer close(scraperDone)
+		scraper.Start(ctx)
+	}()
+
+	go func() {
+		log.Printf("Serving on port: %s\n", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Wait for a shutdown signal, then drain in-flight requests and scraping
+	<-ctx.Done()
+	log.Println("Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server shutdown error: %v\n", err)
+	}
 
-	log.Printf("Serving on port: %s\n", port)
-	log.Fatal(srv.ListenAndServe())
+	select {
+	case <-scraperDone:
+	case <-shutdownCtx.Done():
+		log.Println("Timed out waiting for the scraper to stop")
+	}
 }
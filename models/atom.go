@@ -0,0 +1,151 @@
+package models
+
+import (
+	"html"
+	"strings"
+)
+
+// AtomFeed represents the structure of an Atom 1.0 feed document.
+type AtomFeed struct {
+	Title    AtomText     `xml:"title"`
+	Subtitle AtomText     `xml:"subtitle"`
+	Links    []AtomLink   `xml:"link"`
+	Icon     string       `xml:"icon"`
+	Logo     string       `xml:"logo"`
+	Authors  []AtomPerson `xml:"author"`
+	Entry    []AtomEntry  `xml:"entry"`
+}
+
+// AtomEntry represents an individual entry within an Atom feed.
+type AtomEntry struct {
+	ID        string     `xml:"id"`
+	Title     AtomText   `xml:"title"`
+	Links     []AtomLink `xml:"link"`
+	Summary   AtomText   `xml:"summary"`
+	Content   AtomText   `xml:"http://www.w3.org/2005/Atom content"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published"`
+
+	Authors    []AtomPerson   `xml:"author"`
+	Categories []AtomCategory `xml:"category"`
+
+	MediaGroup struct {
+		Content    []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+		Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+// AtomLink represents an Atom link element.
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// AtomPerson represents an Atom person construct such as an author.
+type AtomPerson struct {
+	Name string `xml:"name"`
+}
+
+// AtomCategory represents an Atom category, whose label is a human-readable form of its term.
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+// AtomText represents an Atom text construct (text, html or xhtml).
+type AtomText struct {
+	Type     string `xml:"type,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+// Value returns the content of the text construct as HTML, keeping the markup of html and
+// xhtml content and escaping plain text, which is the default type.
+func (t AtomText) Value() string {
+	switch t.Type {
+	case "html", "text/html":
+		return strings.TrimSpace(t.Text)
+	case "xhtml", "application/xhtml+xml":
+		return strings.TrimSpace(t.InnerXML)
+	default:
+		return html.EscapeString(strings.TrimSpace(t.Text))
+	}
+}
+
+// AlternateLink returns the href of the first alternate link, falling back to the first link.
+func AlternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+// ToRSSFeed maps an Atom feed onto the RSSFeed structure used by the scraper.
+func (f *AtomFeed) ToRSSFeed() *RSSFeed {
+	var rssFeed RSSFeed
+	rssFeed.Channel.Title = f.Title.Value()
+	rssFeed.Channel.Link = AlternateLink(f.Links)
+	rssFeed.Channel.Description = f.Subtitle.Value()
+	rssFeed.Channel.Image.URL = strings.TrimSpace(f.Logo)
+	rssFeed.Channel.Icon = strings.TrimSpace(f.Icon)
+	rssFeed.Channel.AtomLinks = f.Links
+
+	for _, entry := range f.Entry {
+		// Prefer the summary and fall back to the full content
+		description := entry.Summary.Value()
+		if description == "" {
+			description = entry.Content.Value()
+		}
+
+		item := RSSItem{
+			Title:       entry.Title.Value(),
+			Link:        AlternateLink(entry.Links),
+			Description: description,
+			Published:   strings.TrimSpace(entry.Published),
+			Updated:     strings.TrimSpace(entry.Updated),
+			GUID:        strings.TrimSpace(entry.ID),
+		}
+
+		// Entries without an author inherit the authors of the feed
+		authors := entry.Authors
+		if len(authors) == 0 {
+			authors = f.Authors
+		}
+		for _, author := range authors {
+			item.Creators = append(item.Creators, author.Name)
+		}
+		for _, category := range entry.Categories {
+			if category.Label != "" {
+				item.Categories = append(item.Categories, category.Label)
+			} else {
+				item.Categories = append(item.Categories, category.Term)
+			}
+		}
+
+		// Atom attaches media files as links with rel="enclosure" and
+		// points at the discussion of an entry with rel="replies"
+		for _, link := range entry.Links {
+			switch link.Rel {
+			case "enclosure":
+				item.Enclosures = append(item.Enclosures, RSSEnclosure{URL: link.Href, Type: link.Type, Length: link.Length})
+			case "replies":
+				if item.Comments == "" && (link.Type == "" || link.Type == "text/html") {
+					item.Comments = link.Href
+				}
+			}
+		}
+		item.MediaGroup.Content = entry.MediaGroup.Content
+		item.MediaGroup.Thumbnails = entry.MediaGroup.Thumbnails
+
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, item)
+	}
+
+	return &rssFeed
+}
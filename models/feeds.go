@@ -7,27 +7,86 @@ import (
 	"github.com/qmranik/rss-aggregator-backend/internal/database"
 )
 
+// Feed status values reported on the Feed API response.
+const (
+	FeedStatusOK       = "ok"                // The last fetch succeeded or the feed has not been fetched yet.
+	FeedStatusFailing  = "failing"           // The feed is failing and being retried with backoff.
+	FeedStatusDisabled = "disabled"          // The feed failed too many times in a row and is no longer fetched.
+	FeedStatusBlocked  = "blocked_by_robots" // The feed's robots.txt does not allow us to fetch it.
+	FeedStatusGone     = "gone"              // The feed's server answered 410 Gone and it is no longer fetched.
+)
+
 // Feed represents an RSS feed.
 type Feed struct {
-	ID            uuid.UUID  `json:"id"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
-	Name          string     `json:"name"`
-	Url           string     `json:"url"`
-	UserID        uuid.UUID  `json:"user_id"`
-	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	ID                  uuid.UUID  `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	Name                string     `json:"name"`
+	Url                 string     `json:"url"`
+	UserID              uuid.UUID  `json:"user_id"`
+	Title               *string    `json:"title"`
+	Description         *string    `json:"description"`
+	SiteUrl             *string    `json:"site_url"`
+	Language            *string    `json:"language"`
+	ImageUrl            *string    `json:"image_url"`
+	IconUrl             *string    `json:"icon_url"`
+	LastFetchedAt       *time.Time `json:"last_fetched_at"`
+	NextFetchAt         *time.Time `json:"next_fetch_at"`
+	Status              string     `json:"status"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	LastError           *string    `json:"last_error"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	GoneAt              *time.Time `json:"gone_at"`
+	BlockedByRobots     bool       `json:"blocked_by_robots"`
+	FetchFullContent    bool       `json:"fetch_full_content"`
+	IsPrivate           bool       `json:"is_private"`
+	HasCredentials      bool       `json:"has_credentials"`
 }
 
 // DatabaseFeedToFeed converts a database.Feed to a Feed.
 func DatabaseFeedToFeed(feed database.Feed) Feed {
 	return Feed{
-		ID:            feed.ID,
-		CreatedAt:     feed.CreatedAt,
-		UpdatedAt:     feed.UpdatedAt,
-		Name:          feed.Name,
-		Url:           feed.Url,
-		UserID:        feed.UserID,
-		LastFetchedAt: NullTimeToTimePtr(feed.LastFetchedAt),
+		ID:                  feed.ID,
+		CreatedAt:           feed.CreatedAt.UTC(),
+		UpdatedAt:           feed.UpdatedAt.UTC(),
+		Name:                feed.Name,
+		Url:                 feed.Url,
+		UserID:              feed.UserID,
+		Title:               NullStringToStringPtr(feed.Title),
+		Description:         NullStringToStringPtr(feed.Description),
+		SiteUrl:             NullStringToStringPtr(feed.SiteUrl),
+		Language:            NullStringToStringPtr(feed.Language),
+		ImageUrl:            NullStringToStringPtr(feed.ImageUrl),
+		IconUrl:             NullStringToStringPtr(feed.IconUrl),
+		LastFetchedAt:       NullTimeToTimePtr(feed.LastFetchedAt),
+		NextFetchAt:         NullTimeToTimePtr(feed.NextFetchAt),
+		Status:              feedStatus(feed),
+		ConsecutiveFailures: feed.ConsecutiveFailures,
+		LastError:           NullStringToStringPtr(feed.LastError),
+		LastSuccessAt:       NullTimeToTimePtr(feed.LastSuccessAt),
+		DisabledAt:          NullTimeToTimePtr(feed.DisabledAt),
+		GoneAt:              NullTimeToTimePtr(feed.GoneAt),
+		BlockedByRobots:     feed.BlockedByRobots,
+		FetchFullContent:    feed.FetchFullContent,
+		IsPrivate:           feed.IsPrivate,
+		HasCredentials:      len(feed.Credentials) > 0,
+	}
+}
+
+// feedStatus derives the health status of a feed from its failure tracking columns.
+func feedStatus(feed database.Feed) string {
+	switch {
+	case feed.GoneAt.Valid:
+		return FeedStatusGone
+	case feed.DisabledAt.Valid:
+		return FeedStatusDisabled
+	case feed.BlockedByRobots:
+		return FeedStatusBlocked
+	case feed.ConsecutiveFailures > 0:
+		return FeedStatusFailing
+	default:
+		return FeedStatusOK
 	}
 }
 
@@ -40,6 +99,68 @@ func DatabaseFeedsToFeeds(feeds []database.Feed) []Feed {
 	return result
 }
 
+// Feed credential types.
+const (
+	CredentialBasic  = "basic"  // CredentialBasic authenticates with HTTP Basic auth.
+	CredentialBearer = "bearer" // CredentialBearer sends a bearer token in the Authorization header.
+	CredentialHeader = "header" // CredentialHeader sends a custom header.
+)
+
+// FeedCredentials authenticate the requests for a private feed. They are stored
+// encrypted and never returned by the API.
+type FeedCredentials struct {
+	Type        string `json:"type"`
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	Token       string `json:"token,omitempty"`
+	HeaderName  string `json:"header_name,omitempty"`
+	HeaderValue string `json:"header_value,omitempty"`
+}
+
+// FeedFetch represents a single fetch attempt in the history of a feed.
+type FeedFetch struct {
+	ID            uuid.UUID `json:"id"`
+	FeedID        uuid.UUID `json:"feed_id"`
+	FetchedAt     time.Time `json:"fetched_at"`
+	StatusCode    *int32    `json:"status_code"`
+	DurationMs    int32     `json:"duration_ms"`
+	Bytes         int64     `json:"bytes"`
+	ItemsSeen     int32     `json:"items_seen"`
+	ItemsInserted int32     `json:"items_inserted"`
+	Error         *string   `json:"error"`
+}
+
+// DatabaseFeedFetchToFeedFetch converts a database.FeedFetch to a FeedFetch.
+func DatabaseFeedFetchToFeedFetch(fetch database.FeedFetch) FeedFetch {
+	return FeedFetch{
+		ID:            fetch.ID,
+		FeedID:        fetch.FeedID,
+		FetchedAt:     fetch.FetchedAt.UTC(),
+		StatusCode:    NullInt32ToInt32Ptr(fetch.StatusCode),
+		DurationMs:    fetch.DurationMs,
+		Bytes:         fetch.Bytes,
+		ItemsSeen:     fetch.ItemsSeen,
+		ItemsInserted: fetch.ItemsInserted,
+		Error:         NullStringToStringPtr(fetch.Error),
+	}
+}
+
+// DatabaseFeedFetchesToFeedFetches converts a slice of database.FeedFetch to a slice of FeedFetch.
+func DatabaseFeedFetchesToFeedFetches(fetches []database.FeedFetch) []FeedFetch {
+	result := make([]FeedFetch, len(fetches))
+	for i, fetch := range fetches {
+		result[i] = DatabaseFeedFetchToFeedFetch(fetch)
+	}
+	return result
+}
+
+// FeedCandidate represents a feed discovered from a web page.
+type FeedCandidate struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
 // FeedFollow represents a user's follow relationship with an RSS feed.
 type FeedFollow struct {
 	ID        uuid.UUID `json:"id"`
@@ -53,8 +174,8 @@ type FeedFollow struct {
 func DatabaseFeedFollowToFeedFollow(feedFollow database.FeedFollow) FeedFollow {
 	return FeedFollow{
 		ID:        feedFollow.ID,
-		CreatedAt: feedFollow.CreatedAt,
-		UpdatedAt: feedFollow.UpdatedAt,
+		CreatedAt: feedFollow.CreatedAt.UTC(),
+		UpdatedAt: feedFollow.UpdatedAt.UTC(),
 		UserID:    feedFollow.UserID,
 		FeedID:    feedFollow.FeedID,
 	}
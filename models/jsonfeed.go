@@ -0,0 +1,152 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// JSONFeed represents the structure of a JSON Feed (version 1.0 or 1.1) document.
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description"`
+	Language    string         `json:"language"`
+	Icon        string         `json:"icon"`
+	Favicon     string         `json:"favicon"`
+	Hubs        []JSONFeedHub  `json:"hubs"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+// JSONFeedHub represents an endpoint that can be used to subscribe to real-time notifications of the feed.
+type JSONFeedHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// JSONFeedItem represents an individual item within a JSON Feed.
+type JSONFeedItem struct {
+	ID            JSONFeedID `json:"id"`
+	URL           string     `json:"url"`
+	ExternalURL   string     `json:"external_url"`
+	Title         string     `json:"title"`
+	ContentHTML   string     `json:"content_html"`
+	ContentText   string     `json:"content_text"`
+	Summary       string     `json:"summary"`
+	DatePublished string     `json:"date_published"`
+	DateModified  string     `json:"date_modified"`
+	Image         string     `json:"image"`
+
+	Tags        []string             `json:"tags"`
+	Author      *JSONFeedAuthor      `json:"author"`
+	Authors     []JSONFeedAuthor     `json:"authors"`
+	Attachments []JSONFeedAttachment `json:"attachments"`
+}
+
+// JSONFeedID is the id of a JSON Feed item. The specification requires a string,
+// numeric ids are accepted as well and kept as written.
+type JSONFeedID string
+
+// UnmarshalJSON decodes an id given as a string or a number.
+func (id *JSONFeedID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = JSONFeedID(s)
+		return nil
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return fmt.Errorf("JSON Feed item id must be a string or a number: %w", err)
+	}
+	*id = JSONFeedID(number.String())
+	return nil
+}
+
+// JSONFeedAuthor represents the author of a JSON Feed item, a single object
+// in version 1.0 and a list in version 1.1.
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// JSONFeedAttachment represents a media file attached to a JSON Feed item.
+type JSONFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+// ToRSSFeed maps a JSON Feed onto the RSSFeed structure used by the scraper.
+func (f *JSONFeed) ToRSSFeed() *RSSFeed {
+	var rssFeed RSSFeed
+	rssFeed.Channel.Title = f.Title
+	rssFeed.Channel.Link = f.HomePageURL
+	rssFeed.Channel.Description = f.Description
+	rssFeed.Channel.Language = f.Language
+	rssFeed.Channel.Image.URL = strings.TrimSpace(f.Icon)
+	rssFeed.Channel.Icon = strings.TrimSpace(f.Favicon)
+	for _, hub := range f.Hubs {
+		if strings.EqualFold(hub.Type, "WebSub") {
+			rssFeed.Channel.AtomLinks = append(rssFeed.Channel.AtomLinks, AtomLink{Href: hub.URL, Rel: "hub"})
+		}
+	}
+	if f.FeedURL != "" {
+		rssFeed.Channel.AtomLinks = append(rssFeed.Channel.AtomLinks, AtomLink{Href: f.FeedURL, Rel: "self"})
+	}
+
+	for _, item := range f.Items {
+		// Items may only point at an external article
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		// Descriptions are HTML, plain text fields are escaped so they survive sanitizing
+		description := item.ContentHTML
+		if description == "" {
+			description = html.EscapeString(item.ContentText)
+		}
+		if description == "" {
+			description = html.EscapeString(item.Summary)
+		}
+
+		rssItem := RSSItem{
+			Title:       strings.TrimSpace(item.Title),
+			Link:        strings.TrimSpace(link),
+			Description: description,
+			Published:   strings.TrimSpace(item.DatePublished),
+			Updated:     strings.TrimSpace(item.DateModified),
+			GUID:        strings.TrimSpace(string(item.ID)),
+			Categories:  item.Tags,
+		}
+		authors := item.Authors
+		if len(authors) == 0 && item.Author != nil {
+			authors = []JSONFeedAuthor{*item.Author}
+		}
+		for _, author := range authors {
+			rssItem.Creators = append(rssItem.Creators, author.Name)
+		}
+		for _, attachment := range item.Attachments {
+			rssItem.Enclosures = append(rssItem.Enclosures, RSSEnclosure{
+				URL:    attachment.URL,
+				Type:   attachment.MimeType,
+				Length: strconv.FormatInt(attachment.SizeInBytes, 10),
+			})
+			if attachment.DurationInSeconds > 0 && rssItem.ITunesDuration == "" {
+				rssItem.ITunesDuration = strconv.Itoa(int(attachment.DurationInSeconds))
+			}
+		}
+		if item.Image != "" {
+			rssItem.MediaThumbnails = append(rssItem.MediaThumbnails, MediaThumbnail{URL: item.Image})
+		}
+
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, rssItem)
+	}
+
+	return &rssFeed
+}
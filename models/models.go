@@ -10,8 +10,10 @@ import (
 
 // Parameters represents a set of parameters for feed creation or updates.
 type Parameters struct {
-	Name string `json:"name"`
-	URL  string `json:"url"`
+	Name             string           `json:"name"`
+	URL              string           `json:"url"`
+	FetchFullContent bool             `json:"fetch_full_content"` // FetchFullContent downloads each post's article for its full text.
+	Credentials      *FeedCredentials `json:"credentials"`        // Credentials, if set, authenticate the feed requests and make the feed private.
 }
 
 // UserPram represents user credentials for login or registration.
@@ -34,18 +36,19 @@ type User struct {
 func DatabaseUserToUser(user database.User) User {
 	return User{
 		ID:           user.ID,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
+		CreatedAt:    user.CreatedAt.UTC(),
+		UpdatedAt:    user.UpdatedAt.UTC(),
 		Name:         user.Username,
 		AccessToken:  "", // Assuming you handle tokens elsewhere
 		RefreshToken: "", // Assuming you handle tokens elsewhere
 	}
 }
 
-// NullTimeToTimePtr converts a sql.NullTime to a *time.Time pointer.
+// NullTimeToTimePtr converts a sql.NullTime to a *time.Time pointer in UTC.
 func NullTimeToTimePtr(t sql.NullTime) *time.Time {
 	if t.Valid {
-		return &t.Time
+		utc := t.Time.UTC()
+		return &utc
 	}
 	return nil
 }
@@ -57,3 +60,19 @@ func NullStringToStringPtr(s sql.NullString) *string {
 	}
 	return nil
 }
+
+// NullInt32ToInt32Ptr converts a sql.NullInt32 to a *int32 pointer.
+func NullInt32ToInt32Ptr(n sql.NullInt32) *int32 {
+	if n.Valid {
+		return &n.Int32
+	}
+	return nil
+}
+
+// NullInt64ToInt64Ptr converts a sql.NullInt64 to a *int64 pointer.
+func NullInt64ToInt64Ptr(n sql.NullInt64) *int64 {
+	if n.Valid {
+		return &n.Int64
+	}
+	return nil
+}
@@ -9,27 +9,54 @@ import (
 
 // Post represents a blog post or feed item.
 type Post struct {
-	ID          uuid.UUID  `json:"id"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	Title       string     `json:"title"`
-	Url         string     `json:"url"`
-	Description *string    `json:"description"`
-	PublishedAt *time.Time `json:"published_at"`
-	FeedID      uuid.UUID  `json:"feed_id"`
+	ID          uuid.UUID    `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	Title       string       `json:"title"`
+	Url         string       `json:"url"`
+	Description *string      `json:"description"`
+	Content     *string      `json:"content"`
+	Preview     *string      `json:"preview"`
+	PublishedAt *time.Time   `json:"published_at"`
+	DateSource  string       `json:"date_source"`
+	FeedID      uuid.UUID    `json:"feed_id"`
+	Author      *string      `json:"author"`
+	CommentsUrl *string      `json:"comments_url"`
+	Tags        []string     `json:"tags"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// Attachment represents an enclosure, media file or image attached to a post.
+type Attachment struct {
+	Kind            string  `json:"kind"`
+	Url             string  `json:"url"`
+	MimeType        *string `json:"mime_type"`
+	Length          *int64  `json:"length"`
+	DurationSeconds *int32  `json:"duration_seconds"`
+	Episode         *int32  `json:"episode"`
+	Season          *int32  `json:"season"`
+	Width           *int32  `json:"width"`
+	Height          *int32  `json:"height"`
 }
 
 // DatabasePostToPost converts a database.Post to a Post model.
 func DatabasePostToPost(post database.Post) Post {
 	return Post{
 		ID:          post.ID,
-		CreatedAt:   post.CreatedAt,
-		UpdatedAt:   post.UpdatedAt,
+		CreatedAt:   post.CreatedAt.UTC(),
+		UpdatedAt:   post.UpdatedAt.UTC(),
 		Title:       post.Title,
 		Url:         post.Url,
 		Description: NullStringToStringPtr(post.Description),
+		Content:     NullStringToStringPtr(post.Content),
+		Preview:     NullStringToStringPtr(post.Preview),
 		PublishedAt: NullTimeToTimePtr(post.PublishedAt),
+		DateSource:  post.DateSource,
 		FeedID:      post.FeedID,
+		Author:      NullStringToStringPtr(post.Author),
+		CommentsUrl: NullStringToStringPtr(post.CommentsUrl),
+		Tags:        []string{},
+		Attachments: []Attachment{},
 	}
 }
 
@@ -41,3 +68,44 @@ func DatabasePostsToPosts(posts []database.Post) []Post {
 	}
 	return result
 }
+
+// DatabaseAttachmentToAttachment converts a database.PostAttachment to an Attachment model.
+func DatabaseAttachmentToAttachment(attachment database.PostAttachment) Attachment {
+	return Attachment{
+		Kind:            attachment.Kind,
+		Url:             attachment.Url,
+		MimeType:        NullStringToStringPtr(attachment.MimeType),
+		Length:          NullInt64ToInt64Ptr(attachment.Length),
+		DurationSeconds: NullInt32ToInt32Ptr(attachment.DurationSeconds),
+		Episode:         NullInt32ToInt32Ptr(attachment.Episode),
+		Season:          NullInt32ToInt32Ptr(attachment.Season),
+		Width:           NullInt32ToInt32Ptr(attachment.Width),
+		Height:          NullInt32ToInt32Ptr(attachment.Height),
+	}
+}
+
+// AttachAttachments adds the given attachments to the posts they belong to.
+func AttachAttachments(posts []Post, attachments []database.PostAttachment) {
+	index := make(map[uuid.UUID]int, len(posts))
+	for i, post := range posts {
+		index[post.ID] = i
+	}
+	for _, attachment := range attachments {
+		if i, ok := index[attachment.PostID]; ok {
+			posts[i].Attachments = append(posts[i].Attachments, DatabaseAttachmentToAttachment(attachment))
+		}
+	}
+}
+
+// AttachTags adds the given tag names to the posts they belong to.
+func AttachTags(posts []Post, tags []database.GetTagsForPostsRow) {
+	index := make(map[uuid.UUID]int, len(posts))
+	for i, post := range posts {
+		index[post.ID] = i
+	}
+	for _, tag := range tags {
+		if i, ok := index[tag.PostID]; ok {
+			posts[i].Tags = append(posts[i].Tags, tag.Name)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package models
+
+import "strings"
+
+// RDFFeed represents the structure of an RSS 1.0 (RDF) document, where items are
+// siblings of the channel element rather than children of it.
+type RDFFeed struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Language    string `xml:"http://purl.org/dc/elements/1.1/ language"`
+	} `xml:"channel"`
+	Image RSSImage  `xml:"image"`
+	Item  []RDFItem `xml:"item"`
+}
+
+// RDFItem represents an individual item within an RSS 1.0 (RDF) document.
+type RDFItem struct {
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creators    []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+}
+
+// ToRSSFeed maps an RSS 1.0 (RDF) document onto the RSSFeed structure used by the scraper.
+func (f *RDFFeed) ToRSSFeed() *RSSFeed {
+	var rssFeed RSSFeed
+	rssFeed.Channel.Title = strings.TrimSpace(f.Channel.Title)
+	rssFeed.Channel.Link = strings.TrimSpace(f.Channel.Link)
+	rssFeed.Channel.Description = strings.TrimSpace(f.Channel.Description)
+	rssFeed.Channel.Language = strings.TrimSpace(f.Channel.Language)
+	rssFeed.Channel.Image.URL = strings.TrimSpace(f.Image.URL)
+
+	for _, item := range f.Item {
+		// rdf:about is the item's identity and usually equals its link
+		link := strings.TrimSpace(item.Link)
+		if link == "" {
+			link = item.About
+		}
+
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			Title:       strings.TrimSpace(item.Title),
+			Link:        link,
+			Description: item.Description,
+			DCDate:      strings.TrimSpace(item.Date),
+			GUID:        item.About,
+			Categories:  item.Subjects,
+			Creators:    item.Creators,
+		})
+	}
+
+	return &rssFeed
+}
@@ -1,20 +1,125 @@
 package models
 
+import "strings"
+
 // RSSFeed represents the structure of an RSS feed's channel element.
 type RSSFeed struct {
-	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Language    string    `xml:"language"`
-		Item        []RSSItem `xml:"item"`
-	} `xml:"channel"`
+	Channel RSSChannel `xml:"channel"`
+}
+
+// RSSChannel represents the channel element of an RSS feed.
+// AtomLinks and ITunesImage must precede Link and Image, otherwise the namespaced
+// elements would overwrite the site link and channel image.
+type RSSChannel struct {
+	AtomLinks   []AtomLink  `xml:"http://www.w3.org/2005/Atom link"`
+	ITunesImage ITunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	Title       string      `xml:"title"`
+	Link        string      `xml:"link"`
+	Description string      `xml:"description"`
+	Language    string      `xml:"language"`
+	Image       RSSImage    `xml:"image"`
+	Icon        string      `xml:"-"` // Icon is a small square icon, set by formats that advertise one.
+	TTL         string      `xml:"ttl"`
+	SkipHours   []string    `xml:"skipHours>hour"`
+	SkipDays    []string    `xml:"skipDays>day"`
+	Item        []RSSItem   `xml:"item"`
+}
+
+// RSSImage represents the image element of an RSS channel.
+type RSSImage struct {
+	URL string `xml:"url"`
 }
 
 // RSSItem represents an individual item within an RSS feed.
 type RSSItem struct {
+	// Namespaced elements named like RSS elements must precede them, otherwise they
+	// would be taken for the RSS elements. They are captured here and ignored.
+	ITunesTitle      string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd title"`
+	ITunesAuthor     string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+	MediaTitle       string     `xml:"http://search.yahoo.com/mrss/ title"`
+	MediaDescription string     `xml:"http://search.yahoo.com/mrss/ description"`
+	MediaCategories  []string   `xml:"http://search.yahoo.com/mrss/ category"`
+	AtomLinks        []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+	SlashComments    string     `xml:"http://purl.org/rss/1.0/modules/slash/ comments"`
+
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	GUID        string `xml:"guid"`
+
+	// Alternative dates, used when pubDate is missing or malformed
+	DCDate    string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Published string `xml:"http://www.w3.org/2005/Atom published"`
+	Updated   string `xml:"http://www.w3.org/2005/Atom updated"`
+
+	Categories []string `xml:"category"`
+	Author     string   `xml:"author"`
+	Creators   []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Comments   string   `xml:"comments"`
+
+	Enclosures      []RSSEnclosure   `xml:"enclosure"`
+	ITunesDuration  string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesEpisode   string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	ITunesSeason    string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd season"`
+	ITunesImage     ITunesImage      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	MediaContent    []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroup      struct {
+		Content    []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+		Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+// RSSEnclosure represents a media file attached to an RSS item, such as a podcast episode.
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// ITunesImage represents the itunes:image artwork of an item.
+type ITunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// MediaContent represents a Media RSS media:content element.
+type MediaContent struct {
+	URL        string           `xml:"url,attr"`
+	Type       string           `xml:"type,attr"`
+	Medium     string           `xml:"medium,attr"`
+	FileSize   string           `xml:"fileSize,attr"`
+	Duration   string           `xml:"duration,attr"`
+	Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// MediaThumbnail represents a Media RSS media:thumbnail element.
+type MediaThumbnail struct {
+	URL    string `xml:"url,attr"`
+	Width  string `xml:"width,attr"`
+	Height string `xml:"height,attr"`
+}
+
+// ImageURL returns the URL of the channel's image, preferring the channel image
+// over podcast artwork.
+func (c *RSSChannel) ImageURL() string {
+	if url := strings.TrimSpace(c.Image.URL); url != "" {
+		return url
+	}
+	return strings.TrimSpace(c.ITunesImage.Href)
+}
+
+// WebSubLinks returns the WebSub hubs and the self URL advertised by a feed document.
+func (c *RSSChannel) WebSubLinks() (hubs []string, self string) {
+	for _, link := range c.AtomLinks {
+		switch link.Rel {
+		case "hub":
+			hubs = append(hubs, link.Href)
+		case "self":
+			if self == "" {
+				self = link.Href
+			}
+		}
+	}
+	return hubs, self
 }
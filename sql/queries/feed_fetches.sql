@@ -0,0 +1,22 @@
+-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches (id, feed_id, fetched_at, status_code, duration_ms, bytes, items_seen, items_inserted, error)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+--
+
+-- name: PruneFeedFetches :exec
+DELETE FROM feed_fetches
+WHERE feed_fetches.feed_id = $1
+AND id NOT IN (
+    SELECT id FROM feed_fetches AS recent
+    WHERE recent.feed_id = $1
+    ORDER BY recent.fetched_at DESC
+    LIMIT $2
+);
+--
+
+-- name: GetFeedFetches :many
+SELECT * FROM feed_fetches
+WHERE feed_id = $1
+ORDER BY fetched_at DESC
+LIMIT $2;
+--
@@ -1,19 +1,143 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, fetch_full_content, is_private, credentials)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
 -- name: GetFeeds :many
-SELECT * FROM feeds;
+SELECT * FROM feeds
+WHERE NOT is_private;
 
--- name: GetNextFeedsToFetch :many
+-- name: GetFeedByID :one
 SELECT * FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT $1;
+WHERE id = $1;
 
--- name: MarkFeedFetched :one
+-- name: ClaimFeedsToFetch :many
 UPDATE feeds
-SET last_fetched_at = NOW(),
+SET lease_owner = sqlc.arg(worker_id)::text,
+lease_expires_at = NOW() + sqlc.arg(lease_seconds)::int * INTERVAL '1 second',
+last_fetched_at = NOW(),
+updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE disabled_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+    AND (lease_expires_at IS NULL OR lease_expires_at <= NOW())
+    ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ReleaseFeedLease :exec
+UPDATE feeds
+SET lease_owner = NULL,
+lease_expires_at = NULL
+WHERE id = $1
+AND lease_owner = $2;
+
+-- name: ExtendFeedLease :exec
+UPDATE feeds
+SET lease_expires_at = NOW() + sqlc.arg(lease_seconds)::int * INTERVAL '1 second'
+WHERE id = sqlc.arg(id)
+AND lease_owner = sqlc.arg(worker_id)::text;
+
+-- name: FinishPostGUIDAdoption :exec
+UPDATE feeds
+SET adopt_post_guids = false
+WHERE id = $1;
+
+-- name: UpdateFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $2,
+last_modified = $3,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: ScheduleFeedFetch :exec
+UPDATE feeds
+SET next_fetch_at = $2,
+poll_interval_seconds = $3,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: RecordFeedFailure :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+last_error = $2,
 updated_at = NOW()
 WHERE id = $1
 RETURNING *;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+last_error = NULL,
+last_success_at = NOW(),
+blocked_by_robots = FALSE,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = NOW(),
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkFeedBlockedByRobots :exec
+UPDATE feeds
+SET blocked_by_robots = TRUE,
+last_error = $2,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetFeedByURL :one
+SELECT * FROM feeds
+WHERE url = $1
+AND NOT is_private;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: MergeFeeds :exec
+WITH moved_follows AS (
+    UPDATE feed_follows
+    SET feed_id = sqlc.arg(target_id),
+    updated_at = NOW()
+    WHERE feed_id = sqlc.arg(source_id)
+    AND user_id NOT IN (SELECT user_id FROM feed_follows WHERE feed_id = sqlc.arg(target_id))
+), moved_posts AS (
+    UPDATE posts
+    SET feed_id = sqlc.arg(target_id)
+    WHERE feed_id = sqlc.arg(source_id)
+    AND guid NOT IN (SELECT guid FROM posts WHERE feed_id = sqlc.arg(target_id))
+)
+DELETE FROM feeds
+WHERE id = sqlc.arg(source_id);
+
+-- name: MarkFeedGone :exec
+UPDATE feeds
+SET gone_at = NOW(),
+disabled_at = NOW(),
+last_error = $2,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2,
+description = $3,
+site_url = $4,
+language = $5,
+image_url = $6,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedIcon :exec
+UPDATE feeds
+SET icon_url = $2,
+icon_checked_at = $3,
+updated_at = NOW()
+WHERE id = $1;
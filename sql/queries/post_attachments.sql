@@ -0,0 +1,25 @@
+-- name: UpsertPostAttachment :exec
+INSERT INTO post_attachments (id, created_at, updated_at, post_id, kind, url, mime_type, length, duration_seconds, episode, season, width, height)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+ON CONFLICT (post_id, url) DO UPDATE
+SET kind = EXCLUDED.kind,
+mime_type = EXCLUDED.mime_type,
+length = EXCLUDED.length,
+duration_seconds = EXCLUDED.duration_seconds,
+episode = EXCLUDED.episode,
+season = EXCLUDED.season,
+width = EXCLUDED.width,
+height = EXCLUDED.height,
+updated_at = EXCLUDED.updated_at;
+--
+
+-- name: GetAttachmentsForPosts :many
+SELECT * FROM post_attachments
+WHERE post_id = ANY(sqlc.arg(post_ids)::uuid[])
+ORDER BY post_id, created_at;
+--
+
+-- name: DeletePostAttachments :exec
+DELETE FROM post_attachments
+WHERE post_id = $1;
+--
@@ -1,9 +1,47 @@
--- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+-- name: UpsertPost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, guid, preview, author, comments_url, date_source, attachments_hash, tags_hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+url = EXCLUDED.url,
+description = EXCLUDED.description,
+published_at = CASE WHEN EXCLUDED.date_source = 'first_seen' THEN posts.published_at ELSE EXCLUDED.published_at END,
+date_source = CASE WHEN EXCLUDED.date_source = 'first_seen' THEN posts.date_source ELSE EXCLUDED.date_source END,
+preview = EXCLUDED.preview,
+author = EXCLUDED.author,
+comments_url = EXCLUDED.comments_url,
+attachments_hash = EXCLUDED.attachments_hash,
+tags_hash = EXCLUDED.tags_hash,
+updated_at = EXCLUDED.updated_at
+WHERE posts.title IS DISTINCT FROM EXCLUDED.title
+OR posts.url IS DISTINCT FROM EXCLUDED.url
+OR posts.description IS DISTINCT FROM EXCLUDED.description
+OR (EXCLUDED.date_source <> 'first_seen' AND posts.published_at IS DISTINCT FROM EXCLUDED.published_at)
+OR posts.author IS DISTINCT FROM EXCLUDED.author
+OR posts.comments_url IS DISTINCT FROM EXCLUDED.comments_url
+OR posts.attachments_hash IS DISTINCT FROM EXCLUDED.attachments_hash
+OR posts.tags_hash IS DISTINCT FROM EXCLUDED.tags_hash
 RETURNING *;
 --
 
+-- name: AdoptPostGUID :exec
+UPDATE posts
+SET guid = $3
+WHERE id = (
+    SELECT id FROM posts
+    WHERE feed_id = $1 AND url = $2 AND guid = url
+    ORDER BY created_at
+    LIMIT 1
+)
+AND NOT EXISTS (SELECT 1 FROM posts WHERE feed_id = $1 AND guid = $3);
+--
+
+-- name: UpdatePostContent :exec
+UPDATE posts
+SET content = $2
+WHERE id = $1;
+--
+
 -- name: GetPostsForUser :many
 SELECT posts.* FROM posts
 JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
@@ -11,3 +49,19 @@ WHERE feed_follows.user_id = $1
 ORDER BY posts.published_at DESC
 LIMIT $2;
 --
+
+-- name: GetUnsanitizedPosts :many
+SELECT * FROM posts
+WHERE NOT sanitized
+ORDER BY id
+LIMIT $1;
+--
+
+-- name: UpdatePostSanitized :exec
+UPDATE posts
+SET description = $2,
+preview = $3,
+content = $4,
+sanitized = true
+WHERE id = $1;
+--
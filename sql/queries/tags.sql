@@ -0,0 +1,25 @@
+-- name: UpsertTag :one
+INSERT INTO tags (id, created_at, name)
+VALUES ($1, $2, $3)
+ON CONFLICT (name) DO UPDATE
+SET name = EXCLUDED.name
+RETURNING *;
+--
+
+-- name: AddPostTag :exec
+INSERT INTO post_tags (post_id, tag_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+--
+
+-- name: DeletePostTags :exec
+DELETE FROM post_tags
+WHERE post_id = $1;
+--
+
+-- name: GetTagsForPosts :many
+SELECT post_tags.post_id, tags.name FROM post_tags
+JOIN tags ON tags.id = post_tags.tag_id
+WHERE post_tags.post_id = ANY(sqlc.arg(post_ids)::uuid[])
+ORDER BY tags.name;
+--
@@ -0,0 +1,49 @@
+-- name: GetWebSubSubscription :one
+SELECT * FROM websub_subscriptions
+WHERE feed_id = $1;
+--
+
+-- name: UpsertWebSubSubscription :exec
+INSERT INTO websub_subscriptions (feed_id, created_at, updated_at, hub, topic, secret, state, requested_at)
+VALUES ($1, $2, $3, $4, $5, $6, 'pending', $3)
+ON CONFLICT (feed_id) DO UPDATE
+SET state = CASE
+    WHEN websub_subscriptions.state = 'active'
+    AND websub_subscriptions.hub = EXCLUDED.hub
+    AND websub_subscriptions.topic = EXCLUDED.topic
+    THEN 'active'
+    ELSE 'pending'
+END,
+hub = EXCLUDED.hub,
+topic = EXCLUDED.topic,
+secret = EXCLUDED.secret,
+updated_at = EXCLUDED.updated_at,
+requested_at = EXCLUDED.requested_at;
+--
+
+-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET state = 'active',
+lease_expires_at = $2,
+requested_at = NULL,
+updated_at = NOW()
+WHERE feed_id = $1;
+--
+
+-- name: UpdateWebSubSubscriptionState :exec
+UPDATE websub_subscriptions
+SET state = $2,
+requested_at = NULL,
+updated_at = NOW()
+WHERE feed_id = $1;
+--
+
+-- name: GetWebSubSubscriptionsToRenew :many
+SELECT * FROM websub_subscriptions
+WHERE state = 'active'
+AND hub LIKE 'https://%'
+AND lease_expires_at <= $1
+AND updated_at <= $2
+ORDER BY lease_expires_at ASC
+LIMIT $3;
+--
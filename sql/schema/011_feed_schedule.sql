@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN next_fetch_at TIMESTAMP,
+    ADD COLUMN poll_interval_seconds INTEGER NOT NULL DEFAULT 3600;
+
+CREATE INDEX feeds_next_fetch_at_idx ON feeds (next_fetch_at);
+
+-- +goose Down
+DROP INDEX feeds_next_fetch_at_idx;
+
+ALTER TABLE feeds
+    DROP COLUMN next_fetch_at,
+    DROP COLUMN poll_interval_seconds;
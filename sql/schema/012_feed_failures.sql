@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN last_error TEXT,
+    ADD COLUMN last_success_at TIMESTAMP,
+    ADD COLUMN disabled_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN consecutive_failures,
+    DROP COLUMN last_error,
+    DROP COLUMN last_success_at,
+    DROP COLUMN disabled_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE post_attachments (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    url TEXT NOT NULL,
+    mime_type TEXT,
+    length BIGINT,
+    duration_seconds INTEGER,
+    episode INTEGER,
+    season INTEGER,
+    width INTEGER,
+    height INTEGER,
+    UNIQUE (post_id, url)
+);
+
+-- +goose Down
+DROP TABLE post_attachments;
@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN author TEXT;
+ALTER TABLE posts ADD COLUMN comments_url TEXT;
+CREATE INDEX posts_author_idx ON posts (author);
+
+CREATE TABLE tags (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE post_tags (
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (post_id, tag_id)
+);
+
+-- +goose Down
+DROP TABLE post_tags;
+DROP TABLE tags;
+DROP INDEX posts_author_idx;
+ALTER TABLE posts DROP COLUMN comments_url;
+ALTER TABLE posts DROP COLUMN author;
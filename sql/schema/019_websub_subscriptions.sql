@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE websub_subscriptions (
+    feed_id UUID PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    hub TEXT NOT NULL,
+    topic TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    state TEXT NOT NULL DEFAULT 'pending',
+    lease_expires_at TIMESTAMP
+);
+
+CREATE INDEX websub_subscriptions_lease_expires_at_idx ON websub_subscriptions (lease_expires_at);
+
+-- +goose Down
+DROP TABLE websub_subscriptions;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN title TEXT;
+ALTER TABLE feeds ADD COLUMN description TEXT;
+ALTER TABLE feeds ADD COLUMN site_url TEXT;
+ALTER TABLE feeds ADD COLUMN language TEXT;
+ALTER TABLE feeds ADD COLUMN image_url TEXT;
+ALTER TABLE feeds ADD COLUMN icon_url TEXT;
+ALTER TABLE feeds ADD COLUMN icon_checked_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN icon_checked_at;
+ALTER TABLE feeds DROP COLUMN icon_url;
+ALTER TABLE feeds DROP COLUMN image_url;
+ALTER TABLE feeds DROP COLUMN language;
+ALTER TABLE feeds DROP COLUMN site_url;
+ALTER TABLE feeds DROP COLUMN description;
+ALTER TABLE feeds DROP COLUMN title;
@@ -0,0 +1,84 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN date_source TEXT NOT NULL DEFAULT 'pub_date';
+
+-- Existing values were written as UTC wall-clock times
+ALTER TABLE users
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE feeds
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_fetched_at TYPE TIMESTAMPTZ USING last_fetched_at AT TIME ZONE 'UTC',
+    ALTER COLUMN next_fetch_at TYPE TIMESTAMPTZ USING next_fetch_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_success_at TYPE TIMESTAMPTZ USING last_success_at AT TIME ZONE 'UTC',
+    ALTER COLUMN disabled_at TYPE TIMESTAMPTZ USING disabled_at AT TIME ZONE 'UTC',
+    ALTER COLUMN gone_at TYPE TIMESTAMPTZ USING gone_at AT TIME ZONE 'UTC',
+    ALTER COLUMN lease_expires_at TYPE TIMESTAMPTZ USING lease_expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN icon_checked_at TYPE TIMESTAMPTZ USING icon_checked_at AT TIME ZONE 'UTC';
+
+ALTER TABLE feed_follows
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE posts
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN published_at TYPE TIMESTAMPTZ USING published_at AT TIME ZONE 'UTC';
+
+ALTER TABLE post_attachments
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE tags
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+
+ALTER TABLE websub_subscriptions
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN lease_expires_at TYPE TIMESTAMPTZ USING lease_expires_at AT TIME ZONE 'UTC';
+
+ALTER TABLE feed_fetches
+    ALTER COLUMN fetched_at TYPE TIMESTAMPTZ USING fetched_at AT TIME ZONE 'UTC';
+
+-- +goose Down
+ALTER TABLE feed_fetches
+    ALTER COLUMN fetched_at TYPE TIMESTAMP USING fetched_at AT TIME ZONE 'UTC';
+
+ALTER TABLE websub_subscriptions
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN lease_expires_at TYPE TIMESTAMP USING lease_expires_at AT TIME ZONE 'UTC';
+
+ALTER TABLE tags
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+
+ALTER TABLE post_attachments
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE posts
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN published_at TYPE TIMESTAMP USING published_at AT TIME ZONE 'UTC';
+
+ALTER TABLE feed_follows
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE feeds
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_fetched_at TYPE TIMESTAMP USING last_fetched_at AT TIME ZONE 'UTC',
+    ALTER COLUMN next_fetch_at TYPE TIMESTAMP USING next_fetch_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_success_at TYPE TIMESTAMP USING last_success_at AT TIME ZONE 'UTC',
+    ALTER COLUMN disabled_at TYPE TIMESTAMP USING disabled_at AT TIME ZONE 'UTC',
+    ALTER COLUMN gone_at TYPE TIMESTAMP USING gone_at AT TIME ZONE 'UTC',
+    ALTER COLUMN lease_expires_at TYPE TIMESTAMP USING lease_expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN icon_checked_at TYPE TIMESTAMP USING icon_checked_at AT TIME ZONE 'UTC';
+
+ALTER TABLE users
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE posts DROP COLUMN date_source;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN is_private BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE feeds ADD COLUMN credentials BYTEA;
+
+-- Private feeds may share a URL with a public feed or with other users' private feeds
+ALTER TABLE feeds DROP CONSTRAINT feeds_url_key;
+CREATE UNIQUE INDEX feeds_public_url_idx ON feeds (url) WHERE NOT is_private;
+CREATE UNIQUE INDEX feeds_private_url_idx ON feeds (user_id, url) WHERE is_private;
+
+-- +goose Down
+DELETE FROM feeds WHERE is_private;
+DROP INDEX feeds_private_url_idx;
+DROP INDEX feeds_public_url_idx;
+ALTER TABLE feeds ADD CONSTRAINT feeds_url_key UNIQUE (url);
+ALTER TABLE feeds DROP COLUMN credentials;
+ALTER TABLE feeds DROP COLUMN is_private;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Posts stored before HTML was sanitized on ingest are flagged until the scraper has sanitized them,
+-- new posts are sanitized when they are stored
+ALTER TABLE posts ADD COLUMN sanitized BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE posts ALTER COLUMN sanitized SET DEFAULT true;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN sanitized;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Time of the subscription request awaiting verification by the hub, hubs may only verify outstanding requests
+ALTER TABLE websub_subscriptions ADD COLUMN requested_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE websub_subscriptions DROP COLUMN requested_at;
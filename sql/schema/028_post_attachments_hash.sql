@@ -0,0 +1,7 @@
+-- +goose Up
+-- Hash of the attachments a post was last stored with, so that a post whose attachments changed
+-- is updated like any other change. Posts stored before attachments were tracked get theirs on the next fetch
+ALTER TABLE posts ADD COLUMN attachments_hash TEXT;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN attachments_hash;
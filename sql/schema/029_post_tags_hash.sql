@@ -0,0 +1,7 @@
+-- +goose Up
+-- Hash of the tags a post was last stored with, so that a post whose categories changed
+-- is updated like any other change. Posts stored before tags were tracked get theirs on the next fetch
+ALTER TABLE posts ADD COLUMN tags_hash TEXT;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN tags_hash;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Feeds that may still have posts stored by link before GUIDs were tracked adopt them on ingest,
+-- until all of their items have been stored once
+ALTER TABLE feeds ADD COLUMN adopt_post_guids BOOLEAN NOT NULL DEFAULT false;
+UPDATE feeds SET adopt_post_guids = true
+WHERE EXISTS (SELECT 1 FROM posts WHERE posts.feed_id = feeds.id AND posts.guid = posts.url);
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN adopt_post_guids;